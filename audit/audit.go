@@ -0,0 +1,144 @@
+// The audit package contains an optional local audit log of the calls gcli makes to Vault and the gcert service. Each
+// call is appended to the log as a single line of JSON recording when it was made, what was called, and its outcome.
+// Identifiers which could be used as credentials (i.e. tokens) are only ever recorded as an HMAC, allowing a known
+// value to be matched against the log with Hash without the log revealing it.
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Vault is the type of entries recording a call to the Vault API.
+	Vault = "vault"
+
+	// GRPC is the type of entries recording a call to the gcert service.
+	GRPC = "grpc"
+
+	Success = "success"
+	Failure = "failure"
+)
+
+// keySize is the size of generated HMAC keys in bytes.
+const keySize = 32
+
+// Entry is a single call recorded in the audit log. Identifiers contains the HMAC of each identifier which was used to
+// make the call (i.e. the Vault token).
+type Entry struct {
+	Time        time.Time         `json:"time"`
+	Profile     string            `json:"profile,omitempty"`
+	Type        string            `json:"type"`
+	Operation   string            `json:"operation"`
+	Path        string            `json:"path"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Outcome     string            `json:"outcome"`
+	Status      string            `json:"status,omitempty"`
+	Duration    string            `json:"duration"`
+	Identifiers map[string]string `json:"identifiers,omitempty"`
+}
+
+// Log appends entries to an audit log file. It's safe for concurrent use.
+type Log struct {
+	mu      sync.Mutex
+	file    *os.File
+	key     []byte
+	profile string
+}
+
+// Open opens the audit log at the given path for appending, creating it if it doesn't exist. Identifiers are hashed
+// with the given HMAC key, and each entry records the given profile.
+func Open(path string, key []byte, profile string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return &Log{}, err
+	}
+
+	return &Log{
+		file:    file,
+		key:     key,
+		profile: profile,
+	}, nil
+}
+
+// LoadKey returns the HMAC key stored at the given path. If the file doesn't exist, a new random key is generated and
+// written to it so that entries written by later runs can be correlated.
+func LoadKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return []byte{}, fmt.Errorf("invalid audit key in %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return []byte{}, err
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return []byte{}, err
+	}
+
+	// Fail rather than overwrite a key which was created in the meantime
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		return []byte{}, err
+	}
+
+	return key, nil
+}
+
+// Hash returns the HMAC of the given value using the given key, in the same format identifiers are recorded in.
+func Hash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Hash returns the HMAC of the given value using the key of the Log.
+func (l *Log) Hash(value string) string {
+	return Hash(l.key, value)
+}
+
+// Record appends the given entry to the log. The time and profile are filled in if they aren't set.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Profile == "" {
+		e.Profile = l.profile
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The entry is written with a single call so concurrent processes appending to the same log don't interleave
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.file.Close()
+}
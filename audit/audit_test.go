@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testToken = "s.tG3fu9ZfD6kHqdDbA4fT2hJx"
+
+func newTestLog(t *testing.T) (*Log, string) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	key, err := LoadKey(filepath.Join(dir, "audit.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "audit.log")
+	l, err := Open(path, key, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	return l, path
+}
+
+func readEntries(t *testing.T, path string) ([]Entry, string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, string(data)
+}
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.key")
+
+	key, err := LoadKey(path)
+	assert.Nil(t, err)
+	assert.Len(t, key, keySize)
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadKey(path)
+	assert.Nil(t, err)
+	assert.Equal(t, key, loaded)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("not hex"), 0600))
+	_, err = LoadKey(path)
+	assert.NotNil(t, err)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, Hash([]byte("key"), "value"), Hash([]byte("key"), "value"))
+	assert.NotEqual(t, Hash([]byte("key"), "value"), Hash([]byte("other"), "value"))
+	assert.True(t, strings.HasPrefix(Hash([]byte("key"), "value"), "hmac-sha256:"))
+}
+
+func TestLog_Record(t *testing.T) {
+	l, path := newTestLog(t)
+
+	assert.Nil(t, l.Record(Entry{Type: Vault, Operation: "GET", Path: "secret/test", Outcome: Success}))
+	assert.Nil(t, l.Record(Entry{Type: Vault, Operation: "GET", Path: "secret/other", Outcome: Failure}))
+
+	entries, _ := readEntries(t, path)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "secret/test", entries[0].Path)
+	assert.Equal(t, "test", entries[0].Profile)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, Failure, entries[1].Outcome)
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/secret/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"password": "hunter2"}}`))
+	}))
+	defer server.Close()
+
+	l, path := newTestLog(t)
+	httpClient := &http.Client{Transport: &Transport{Base: http.DefaultTransport, Log: l}}
+
+	for _, p := range []string{"/v1/secret/test", "/v1/secret/missing"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Vault-Token", testToken)
+		req.Header.Set("X-Vault-Namespace", "ns1/")
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	entries, raw := readEntries(t, path)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, Vault, entries[0].Type)
+	assert.Equal(t, "GET", entries[0].Operation)
+	assert.Equal(t, "secret/"+l.Hash("test"), entries[0].Path)
+	assert.Equal(t, "ns1/", entries[0].Namespace)
+	assert.Equal(t, Success, entries[0].Outcome)
+	assert.Equal(t, l.Hash(testToken), entries[0].Identifiers["token"])
+	assert.Equal(t, Failure, entries[1].Outcome)
+	assert.Equal(t, "404 Not Found", entries[1].Status)
+
+	assert.NotContains(t, raw, testToken)
+	assert.NotContains(t, raw, "hunter2")
+}
+
+func TestRedactPath(t *testing.T) {
+	l, _ := newTestLog(t)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"sys/seal-status", "sys/seal-status"},
+		{"sys/wrapping/lookup", "sys/wrapping/lookup"},
+		{"auth/token/lookup-self", "auth/token/lookup-self"},
+		{"identity/oidc/token/gcert", "identity/oidc/token/gcert"},
+		{"auth/userpass/login/alice", "auth/userpass/login/" + l.Hash("alice")},
+		{"auth/ldap/login", "auth/ldap/login"},
+		{"secret/ssl/example.com", "secret/" + l.Hash("ssl/example.com")},
+		{"secret/data/ssl/example.com", "secret/data/" + l.Hash("ssl/example.com")},
+		{"secret/metadata/ssl", "secret/metadata/" + l.Hash("ssl")},
+		{"transit/encrypt/backups", "transit/encrypt/" + l.Hash("backups")},
+		{"transit/datakey/plaintext/backups", "transit/datakey/plaintext/" + l.Hash("backups")},
+		{"pki/revoke", "pki/revoke"},
+		{"secret", "secret"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, RedactPath(l, test.path), test.path)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	l, path := newTestLog(t)
+	interceptor := UnaryClientInterceptor(l)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		if method == "/proto.CertificateService/Fail" {
+			return status.Error(codes.Unavailable, "unavailable")
+		}
+		return nil
+	}
+
+	assert.Nil(t, interceptor(ctx, "/proto.CertificateService/GetCertificate", nil, nil, nil, invoker))
+	assert.NotNil(t, interceptor(context.Background(), "/proto.CertificateService/Fail", nil, nil, nil, invoker))
+
+	entries, raw := readEntries(t, path)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, GRPC, entries[0].Type)
+	assert.Equal(t, "/proto.CertificateService/GetCertificate", entries[0].Path)
+	assert.Equal(t, Success, entries[0].Outcome)
+	assert.Equal(t, "OK", entries[0].Status)
+	assert.Equal(t, l.Hash("Bearer "+testToken), entries[0].Identifiers["authorization"])
+	assert.Equal(t, Failure, entries[1].Outcome)
+	assert.Equal(t, "Unavailable", entries[1].Status)
+	assert.Empty(t, entries[1].Identifiers)
+
+	assert.NotContains(t, raw, testToken)
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/jmgilman/gcli/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// vaultTokenHeader is the header the Vault API client sends the token in.
+const vaultTokenHeader = "X-Vault-Token"
+
+// credentialKeys are the gRPC metadata keys credentials are sent to the gcert service with.
+var credentialKeys = []string{"authorization", "x-vault-token"}
+
+// operations are the path segments following a secrets engine mount which name an operation rather than a secret, key,
+// or role (i.e. the data in secret/data/<name> or the encrypt in transit/encrypt/<key>). They're recorded as is.
+var operations = map[string]bool{
+	"data": true, "metadata": true, "delete": true, "undelete": true, "destroy": true,
+	"encrypt": true, "decrypt": true, "rewrap": true, "sign": true, "verify": true, "datakey": true,
+	"issue": true, "revoke": true, "cert": true, "config": true,
+}
+
+// RedactPath returns the given Vault API path (without the /v1/ prefix) with the identifiers it contains replaced by
+// their HMAC using the key of the given Log. The name logged in with (auth/<mount>/login/<name>) and the name of the
+// secret, key, or role following a secrets engine mount and its operation are hashed as a single value, so a known name
+// can be matched against the log with Hash. System, token, and identity paths don't contain secret names and are
+// returned as is.
+func RedactPath(l *Log, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch segments[0] {
+	case "sys", "identity":
+		return path
+	case "auth":
+		for i := 1; i < len(segments)-1; i++ {
+			if segments[i] == "login" {
+				return hashTail(l, segments, i+1)
+			}
+		}
+		return path
+	}
+
+	i := 1
+	if i < len(segments) && operations[segments[i]] {
+		i++
+		// Transit data keys are generated at datakey/<plaintext|wrapped>/<key>
+		if segments[i-1] == "datakey" && i < len(segments)-1 {
+			i++
+		}
+	}
+	return hashTail(l, segments, i)
+}
+
+// hashTail joins the given path segments, replacing those from the given index onwards with their HMAC.
+func hashTail(l *Log, segments []string, from int) string {
+	if from >= len(segments) {
+		return strings.Join(segments, "/")
+	}
+	return strings.Join(append(segments[:from:from], l.Hash(strings.Join(segments[from:], "/"))), "/")
+}
+
+// Transport wraps an http.RoundTripper, recording every request made to the Vault API in the given Log. Only the
+// request path with its identifiers hashed by RedactPath, the namespace, and the HMAC of the token are recorded; request
+// and response bodies never are.
+type Transport struct {
+	Base http.RoundTripper
+	Log  *Log
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(r)
+
+	entry := Entry{
+		Type:      Vault,
+		Operation: r.Method,
+		Path:      RedactPath(t.Log, strings.TrimPrefix(r.URL.Path, "/v1/")),
+		Namespace: r.Header.Get(consts.NamespaceHeaderName),
+		Outcome:   Success,
+		Duration:  time.Since(start).String(),
+	}
+
+	if token := r.Header.Get(vaultTokenHeader); token != "" {
+		entry.Identifiers = map[string]string{"token": t.Log.Hash(token)}
+	}
+
+	switch {
+	case err != nil:
+		entry.Outcome = Failure
+		entry.Status = "unable to connect"
+	case resp.StatusCode >= 400:
+		entry.Outcome = Failure
+		entry.Status = resp.Status
+	default:
+		entry.Status = resp.Status
+	}
+
+	record(t.Log, entry)
+	return resp, err
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor which records every call made to the gcert service in the given
+// Log. Any authorization metadata sent with the call is recorded as an HMAC.
+func UnaryClientInterceptor(l *Log) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		entry := Entry{
+			Type:      GRPC,
+			Operation: "unary",
+			Path:      method,
+			Outcome:   Success,
+			Status:    status.Code(err).String(),
+			Duration:  time.Since(start).String(),
+		}
+		if err != nil {
+			entry.Outcome = Failure
+		}
+
+		if md, ok := metadata.FromOutgoingContext(ctx); ok {
+			for _, key := range credentialKeys {
+				if values := md.Get(key); len(values) > 0 {
+					if entry.Identifiers == nil {
+						entry.Identifiers = map[string]string{}
+					}
+					entry.Identifiers[key] = l.Hash(values[0])
+				}
+			}
+		}
+
+		record(l, entry)
+		return err
+	}
+}
+
+// record appends the given entry to the log. A failure to write the audit log is logged rather than failing the call
+// which was already made.
+func record(l *Log, e Entry) {
+	if err := l.Record(e); err != nil {
+		logging.L().Named("audit").Warn("unable to write audit log entry", "path", e.Path, "error", err)
+	}
+}
@@ -0,0 +1,64 @@
+// The cert package contains types and functions for working with the SSL certificates that the gcert service writes to
+// Vault.
+package cert
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// BasePath is the Vault path that the gcert service writes certificates under. Each domain is written to
+// [BasePath]/[domain].
+const BasePath = "secret/ssl/"
+
+// Certificate represents the certificate details written to Vault by the gcert service for a single domain.
+type Certificate struct {
+	Domain            string
+	CertURL           string
+	CertStableURL     string
+	SerialNumber      string
+	Certificate       []byte
+	PrivateKey        []byte
+	IssuerCertificate []byte
+}
+
+// Path returns the Vault path where the gcert service writes the certificate for the given domain.
+func Path(domain string) string {
+	return BasePath + domain
+}
+
+// NewCertificateFromData returns a new Certificate for the given domain using the secret data read from Vault. The
+// gcert service base64 encodes the certificate, private key, and issuer certificate so they are decoded here.
+func NewCertificateFromData(domain string, data map[string]interface{}) (*Certificate, error) {
+	c := &Certificate{
+		Domain: domain,
+	}
+
+	c.CertURL, _ = data["cert_url"].(string)
+	c.CertStableURL, _ = data["cert_stable_url"].(string)
+
+	fields := map[string]*[]byte{
+		"certificate":        &c.Certificate,
+		"private_key":        &c.PrivateKey,
+		"issuer_certificate": &c.IssuerCertificate,
+	}
+	for name, field := range fields {
+		encoded, ok := data[name].(string)
+		if !ok {
+			return &Certificate{}, fmt.Errorf("certificate data for %s is missing the %s field", domain, name)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return &Certificate{}, fmt.Errorf("unable to decode the %s field for %s: %w", name, domain, err)
+		}
+		*field = decoded
+	}
+
+	// The gcert service doesn't store the serial number, so it's read from the certificate when it can be parsed
+	if parsed, err := c.X509(); err == nil {
+		c.SerialNumber = FormatSerial(parsed.SerialNumber)
+	}
+
+	return c, nil
+}
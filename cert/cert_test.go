@@ -0,0 +1,47 @@
+package cert
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newData() map[string]interface{} {
+	return map[string]interface{}{
+		"cert_url":           "https://acme/cert",
+		"cert_stable_url":    "https://acme/stable",
+		"certificate":        base64.StdEncoding.EncodeToString([]byte("certificate")),
+		"private_key":        base64.StdEncoding.EncodeToString([]byte("private key")),
+		"issuer_certificate": base64.StdEncoding.EncodeToString([]byte("issuer")),
+	}
+}
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "secret/ssl/test.example.com", Path("test.example.com"))
+}
+
+func TestNewCertificateFromData(t *testing.T) {
+	t.Run("Test with valid data", func(t *testing.T) {
+		c, err := NewCertificateFromData("test.example.com", newData())
+		assert.Nil(t, err)
+		assert.Equal(t, "test.example.com", c.Domain)
+		assert.Equal(t, "https://acme/cert", c.CertURL)
+		assert.Equal(t, []byte("certificate"), c.Certificate)
+		assert.Equal(t, []byte("private key"), c.PrivateKey)
+		assert.Equal(t, []byte("issuer"), c.IssuerCertificate)
+	})
+
+	t.Run("Test with missing field", func(t *testing.T) {
+		data := newData()
+		delete(data, "private_key")
+		_, err := NewCertificateFromData("test.example.com", data)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test with invalid encoding", func(t *testing.T) {
+		data := newData()
+		data["certificate"] = "!!not base64!!"
+		_, err := NewCertificateFromData("test.example.com", data)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,137 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultTLSPort is the port connected to when the address given to Inspect doesn't include one.
+const DefaultTLSPort = "443"
+
+// The statuses of an Endpoint returned by Status.
+const (
+	// EndpointOK means the endpoint serves a trusted certificate which hasn't expired and matches the one in Vault.
+	EndpointOK = "ok"
+
+	// EndpointExpired means the endpoint serves an expired certificate.
+	EndpointExpired = "expired"
+
+	// EndpointStale means the endpoint serves a different certificate than the one in Vault.
+	EndpointStale = "stale"
+
+	// EndpointUntrusted means the chain served by the endpoint couldn't be verified for its server name.
+	EndpointUntrusted = "untrusted"
+)
+
+// Endpoint is the certificate chain served by a TLS endpoint.
+type Endpoint struct {
+	Address    string
+	ServerName string
+	Chain      []*x509.Certificate
+
+	// VerifyError is the reason the chain couldn't be verified for the server name, or nil if it was verified.
+	VerifyError error
+}
+
+// Leaf returns the certificate served by the Endpoint, the first in its chain.
+func (e *Endpoint) Leaf() *x509.Certificate {
+	if len(e.Chain) == 0 {
+		return &x509.Certificate{}
+	}
+	return e.Chain[0]
+}
+
+// Status returns the status of the Endpoint at the given time, compared with the given certificate stored in Vault (or
+// nil if there isn't one). An expired certificate takes precedence over a stale one, which takes precedence over an
+// untrusted chain, since renewing or deploying the certificate may resolve the others.
+func (e *Endpoint) Status(stored *x509.Certificate, now time.Time) string {
+	leaf := e.Leaf()
+	switch {
+	case !leaf.NotAfter.After(now):
+		return EndpointExpired
+	case stored != nil && !bytes.Equal(stored.Raw, leaf.Raw):
+		return EndpointStale
+	case e.VerifyError != nil:
+		return EndpointUntrusted
+	default:
+		return EndpointOK
+	}
+}
+
+// TLSAddress returns the given address with DefaultTLSPort added if it doesn't include a port.
+func TLSAddress(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), DefaultTLSPort)
+}
+
+// Inspect performs a TLS handshake with the given address (host:port) and returns the certificate chain it serves. The
+// server name sent with SNI defaults to the host of the address. The chain is verified for the server name against the
+// given roots, or the system roots if nil, but is returned even if it's expired or untrusted so it can be reported.
+func Inspect(ctx context.Context, address string, serverName string, roots *x509.CertPool) (*Endpoint, error) {
+	address = TLSAddress(address)
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return &Endpoint{}, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return &Endpoint{}, err
+		}
+	}
+
+	// Abort the handshake if the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	// Verification is done below so the chain can still be inspected when it fails
+	client := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		if ctx.Err() != nil {
+			return &Endpoint{}, ctx.Err()
+		}
+		return &Endpoint{}, fmt.Errorf("TLS handshake with %s failed: %w", address, err)
+	}
+
+	endpoint := &Endpoint{
+		Address:    address,
+		ServerName: serverName,
+		Chain:      client.ConnectionState().PeerCertificates,
+	}
+	if len(endpoint.Chain) == 0 {
+		return &Endpoint{}, fmt.Errorf("%s didn't serve a certificate", address)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range endpoint.Chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, endpoint.VerifyError = endpoint.Chain[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+
+	return endpoint, nil
+}
@@ -0,0 +1,115 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTLSAddress(t *testing.T) {
+	assert.Equal(t, "example.com:8443", TLSAddress("example.com:8443"))
+	assert.Equal(t, "example.com:443", TLSAddress("example.com"))
+	assert.Equal(t, "[::1]:443", TLSAddress("::1"))
+	assert.Equal(t, "[::1]:443", TLSAddress("[::1]"))
+}
+
+func TestInspect(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	address := server.Listener.Addr().String()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	t.Run("Test trusted chain", func(t *testing.T) {
+		endpoint, err := Inspect(context.Background(), address, "", roots)
+		assert.Nil(t, err)
+		assert.Equal(t, address, endpoint.Address)
+		assert.Equal(t, "127.0.0.1", endpoint.ServerName)
+		assert.Equal(t, server.Certificate().Raw, endpoint.Leaf().Raw)
+		assert.Nil(t, endpoint.VerifyError)
+	})
+	t.Run("Test server name", func(t *testing.T) {
+		endpoint, err := Inspect(context.Background(), address, "example.com", roots)
+		assert.Nil(t, err)
+		assert.Nil(t, endpoint.VerifyError)
+
+		endpoint, err = Inspect(context.Background(), address, "other.test", roots)
+		assert.Nil(t, err)
+		assert.NotNil(t, endpoint.VerifyError)
+	})
+	t.Run("Test untrusted chain", func(t *testing.T) {
+		endpoint, err := Inspect(context.Background(), address, "", x509.NewCertPool())
+		assert.Nil(t, err)
+		assert.Equal(t, server.Certificate().SerialNumber, endpoint.Leaf().SerialNumber)
+		assert.NotNil(t, endpoint.VerifyError)
+	})
+	t.Run("Test plain TCP server", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer plain.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := Inspect(ctx, plain.Listener.Addr().String(), "", roots)
+		assert.NotNil(t, err)
+	})
+	t.Run("Test closed server", func(t *testing.T) {
+		closed := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		closedAddress := closed.Listener.Addr().String()
+		closed.Close()
+
+		_, err := Inspect(context.Background(), closedAddress, "", roots)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEndpoint_Status(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	address := server.Listener.Addr().String()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	trusted, err := Inspect(context.Background(), address, "", roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := Inspect(context.Background(), address, "other.test", roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := newTestChain(t, "example.com", "ec")
+	otherCertificate, err := other.X509()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	expiry := server.Certificate().NotAfter.Add(time.Hour)
+	tests := []struct {
+		name     string
+		endpoint *Endpoint
+		stored   *x509.Certificate
+		now      time.Time
+		expected string
+	}{
+		{"trusted and matching Vault", trusted, server.Certificate(), now, EndpointOK},
+		{"no certificate in Vault", trusted, nil, now, EndpointOK},
+		{"different certificate in Vault", trusted, otherCertificate, now, EndpointStale},
+		{"untrusted for the server name", untrusted, server.Certificate(), now, EndpointUntrusted},
+		{"stale takes precedence over untrusted", untrusted, otherCertificate, now, EndpointStale},
+		{"expired", trusted, server.Certificate(), expiry, EndpointExpired},
+		{"expired takes precedence over stale", untrusted, otherCertificate, expiry, EndpointExpired},
+	}
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.endpoint.Status(test.stored, test.now))
+		})
+	}
+}
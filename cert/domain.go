@@ -0,0 +1,119 @@
+package cert
+
+import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"strings"
+)
+
+// domainProfile converts internationalized domain names to their ASCII (punycode) form using the non-transitional
+// IDNA2008 rules browsers use (i.e. faß.de isn't mapped to fass.de), rejecting characters which aren't valid in a host
+// name. New profiles are non-transitional by default, unlike idna.Lookup.
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+)
+
+// Problem describes why a domain can't be requested.
+type Problem struct {
+	Domain string
+	Reason string
+}
+
+func (p Problem) Error() string {
+	return p.Domain + ": " + p.Reason
+}
+
+// NormalizeDomain returns the given domain in the form certificates are requested for: lowercase, converted to
+// punycode if it's internationalized, and without a trailing dot. An error is returned describing why the domain can't
+// be requested if it isn't a fully qualified domain name. A wildcard is only allowed as the entire leftmost label, and
+// must cover a domain with at least two labels (i.e. *.example.com but not *.com).
+func NormalizeDomain(domain string) (string, error) {
+	name := strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if name == "" {
+		return "", fmt.Errorf("domain is empty")
+	}
+	if net.ParseIP(strings.Trim(name, "[]")) != nil {
+		return "", fmt.Errorf("IP addresses can't be requested, only domain names")
+	}
+
+	wildcard := strings.HasPrefix(name, "*.")
+	base := strings.TrimPrefix(name, "*.")
+	if strings.Contains(base, "*") {
+		return "", fmt.Errorf("a wildcard can only be the entire leftmost label (i.e. *.example.com)")
+	}
+
+	ascii, err := domainProfile.ToASCII(base)
+	if err != nil {
+		return "", fmt.Errorf("not a valid domain name: %w", err)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		if wildcard {
+			return "", fmt.Errorf("a wildcard must cover a domain with at least two labels (i.e. *.example.com)")
+		}
+		return "", fmt.Errorf("not a fully qualified domain name")
+	}
+	for _, label := range labels {
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", fmt.Errorf("label %q can't start or end with a hyphen", label)
+		}
+	}
+
+	if wildcard {
+		return "*." + ascii, nil
+	}
+	return ascii, nil
+}
+
+// InZone returns true if the given normalized domain is the given zone or a subdomain of it. A wildcard domain is in
+// the zone of the domain it covers. Internationalized zones are compared in their punycode form.
+func InZone(domain string, zone string) bool {
+	domain = strings.TrimPrefix(domain, "*.")
+	zone = strings.TrimSuffix(strings.ToLower(zone), ".")
+	if ascii, err := domainProfile.ToASCII(zone); err == nil {
+		zone = ascii
+	}
+	return domain == zone || strings.HasSuffix(domain, "."+zone)
+}
+
+// ValidateDomains normalizes each of the given domains with NormalizeDomain, removing duplicates while keeping the
+// order of the rest. If any zones are given, each domain must also be in one of them. The normalized domains are
+// returned along with the duplicates removed and a Problem for every domain which can't be requested.
+func ValidateDomains(domains []string, zones []string) (valid []string, duplicates []string, problems []Problem) {
+	seen := map[string]bool{}
+	for _, domain := range domains {
+		normalized, err := NormalizeDomain(domain)
+		if err != nil {
+			problems = append(problems, Problem{Domain: domain, Reason: err.Error()})
+			continue
+		}
+
+		if seen[normalized] {
+			duplicates = append(duplicates, domain)
+			continue
+		}
+		seen[normalized] = true
+
+		if len(zones) > 0 && !inAnyZone(normalized, zones) {
+			problems = append(problems, Problem{Domain: domain,
+				Reason: fmt.Sprintf("not in a zone served by gcert (%s)", strings.Join(zones, ", "))})
+			continue
+		}
+		valid = append(valid, normalized)
+	}
+	return valid, duplicates, problems
+}
+
+// inAnyZone returns true if the given domain is in one of the given zones.
+func inAnyZone(domain string, zones []string) bool {
+	for _, zone := range zones {
+		if InZone(domain, zone) {
+			return true
+		}
+	}
+	return false
+}
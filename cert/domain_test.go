@@ -0,0 +1,80 @@
+package cert
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		domain   string
+		expected string
+		valid    bool
+	}{
+		{"example.com", "example.com", true},
+		{"WWW.Example.COM.", "www.example.com", true},
+		{" example.com ", "example.com", true},
+		{"*.example.com", "*.example.com", true},
+		{"bücher.example", "xn--bcher-kva.example", true},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example", true},
+		{"faß.de", "xn--fa-hia.de", true},
+		{"", "", false},
+		{".", "", false},
+		{"localhost", "", false},
+		{"192.168.1.1", "", false},
+		{"::1", "", false},
+		{"*.com", "", false},
+		{"*", "", false},
+		{"foo.*.example.com", "", false},
+		{"*.*.example.com", "", false},
+		{"w*.example.com", "", false},
+		{"exa mple.com", "", false},
+		{"under_score.example.com", "", false},
+		{"-foo.example.com", "", false},
+		{"foo-.example.com", "", false},
+		{"foo..example.com", "", false},
+		{"xn--zz.example", "", false},
+		{"a123456789012345678901234567890123456789012345678901234567890123.example.com", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.domain, func(t *testing.T) {
+			result, err := NormalizeDomain(test.domain)
+			if test.valid {
+				assert.Nil(t, err)
+				assert.Equal(t, test.expected, result)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestInZone(t *testing.T) {
+	assert.True(t, InZone("example.com", "example.com"))
+	assert.True(t, InZone("www.example.com", "example.com"))
+	assert.True(t, InZone("*.lab.example.com", "Example.com."))
+	assert.True(t, InZone("xn--bcher-kva.example", "bücher.example"))
+	assert.False(t, InZone("badexample.com", "example.com"))
+	assert.False(t, InZone("example.org", "example.com"))
+}
+
+func TestValidateDomains(t *testing.T) {
+	t.Run("Test duplicates", func(t *testing.T) {
+		valid, duplicates, problems := ValidateDomains([]string{"www.example.com", "example.com", "WWW.example.com."},
+			nil)
+		assert.Equal(t, []string{"www.example.com", "example.com"}, valid)
+		assert.Equal(t, []string{"WWW.example.com."}, duplicates)
+		assert.Empty(t, problems)
+	})
+	t.Run("Test problems", func(t *testing.T) {
+		valid, _, problems := ValidateDomains([]string{"example.com", "*.*.example.com", "example.org", "localhost"},
+			[]string{"example.com"})
+		assert.Equal(t, []string{"example.com"}, valid)
+		assert.Len(t, problems, 3)
+		assert.Equal(t, "*.*.example.com", problems[0].Domain)
+		assert.Equal(t, "example.org", problems[1].Domain)
+		assert.Contains(t, problems[1].Reason, "not in a zone served by gcert")
+		assert.Equal(t, "localhost", problems[2].Domain)
+	})
+}
@@ -0,0 +1,82 @@
+package cert
+
+import (
+	"encoding/base64"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// KubernetesSecretType is the type of the Kubernetes Secrets which hold a TLS certificate and its private key.
+const KubernetesSecretType = "kubernetes.io/tls"
+
+// The annotations added to Kubernetes Secrets to identify the certificate they hold.
+const (
+	DomainAnnotation       = "gcli/domain"
+	SerialNumberAnnotation = "gcli/serial-number"
+	NotAfterAnnotation     = "gcli/not-after"
+)
+
+var (
+	// kubernetesName matches a DNS subdomain name, which most Kubernetes object names (including Secrets) must be.
+	kubernetesName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+	// kubernetesNamespace matches a DNS label, which namespace names must be.
+	kubernetesNamespace = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// KubernetesSecretName returns the default name of the Kubernetes Secret holding the certificate of the given domain.
+// It's the domain itself, with a leading wildcard replaced by "wildcard" (i.e. wildcard.example.com).
+func KubernetesSecretName(domain string) string {
+	name := strings.ToLower(domain)
+	if strings.HasPrefix(name, "*.") {
+		name = "wildcard" + strings.TrimPrefix(name, "*")
+	}
+	return name
+}
+
+// KubernetesSecret returns a YAML encoded Kubernetes Secret of the kubernetes.io/tls type with the given name and
+// namespace, holding the full chain (tls.crt) and private key (tls.key) of the given Certificate. The Secret is
+// annotated with the domain, serial number, and expiry of the certificate.
+func KubernetesSecret(c *Certificate, name string, namespace string) ([]byte, error) {
+	if len(name) > 253 || !kubernetesName.MatchString(name) {
+		return []byte{}, fmt.Errorf("%q is not a valid Kubernetes Secret name (lowercase letters, digits, '-', and '.')",
+			name)
+	}
+	if len(namespace) > 63 || !kubernetesNamespace.MatchString(namespace) {
+		return []byte{}, fmt.Errorf("%q is not a valid Kubernetes namespace (lowercase letters, digits, and '-')",
+			namespace)
+	}
+	if len(c.Certificate) == 0 || len(c.PrivateKey) == 0 {
+		return []byte{}, fmt.Errorf("the certificate for %s is missing its certificate or private key", c.Domain)
+	}
+
+	annotations := yaml.MapSlice{{Key: DomainAnnotation, Value: c.Domain}}
+	if c.SerialNumber != "" {
+		annotations = append(annotations, yaml.MapItem{Key: SerialNumberAnnotation, Value: c.SerialNumber})
+	}
+	if parsed, err := c.X509(); err == nil {
+		annotations = append(annotations, yaml.MapItem{Key: NotAfterAnnotation,
+			Value: parsed.NotAfter.UTC().Format(time.RFC3339)})
+	}
+
+	fullChain := append(append([]byte{}, c.Certificate...), c.IssuerCertificate...)
+	secret := yaml.MapSlice{
+		{Key: "apiVersion", Value: "v1"},
+		{Key: "kind", Value: "Secret"},
+		{Key: "metadata", Value: yaml.MapSlice{
+			{Key: "name", Value: name},
+			{Key: "namespace", Value: namespace},
+			{Key: "annotations", Value: annotations},
+		}},
+		{Key: "type", Value: KubernetesSecretType},
+		{Key: "data", Value: yaml.MapSlice{
+			{Key: "tls.crt", Value: base64.StdEncoding.EncodeToString(fullChain)},
+			{Key: "tls.key", Value: base64.StdEncoding.EncodeToString(c.PrivateKey)},
+		}},
+	}
+
+	return yaml.Marshal(secret)
+}
@@ -0,0 +1,74 @@
+package cert
+
+import (
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"testing"
+	"time"
+)
+
+func TestKubernetesSecretName(t *testing.T) {
+	assert.Equal(t, "example.com", KubernetesSecretName("example.com"))
+	assert.Equal(t, "www.example.com", KubernetesSecretName("WWW.Example.com"))
+	assert.Equal(t, "wildcard.example.com", KubernetesSecretName("*.example.com"))
+}
+
+func TestKubernetesSecret(t *testing.T) {
+	notAfter := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	c := &Certificate{
+		Domain:            "example.com",
+		SerialNumber:      "3a:7f:01",
+		Certificate:       newTestCertificate(t, "example.com", pkix.Name{CommonName: "R3"}, notAfter),
+		PrivateKey:        []byte("key\n"),
+		IssuerCertificate: []byte("issuer\n"),
+	}
+
+	data, err := KubernetesSecret(c, "example-tls", "web")
+	assert.Nil(t, err)
+
+	var secret struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name        string            `yaml:"name"`
+			Namespace   string            `yaml:"namespace"`
+			Annotations map[string]string `yaml:"annotations"`
+		} `yaml:"metadata"`
+		Type string            `yaml:"type"`
+		Data map[string]string `yaml:"data"`
+	}
+	assert.Nil(t, yaml.UnmarshalStrict(data, &secret))
+
+	assert.Equal(t, "v1", secret.APIVersion)
+	assert.Equal(t, "Secret", secret.Kind)
+	assert.Equal(t, "example-tls", secret.Metadata.Name)
+	assert.Equal(t, "web", secret.Metadata.Namespace)
+	assert.Equal(t, KubernetesSecretType, secret.Type)
+	assert.Equal(t, map[string]string{
+		DomainAnnotation:       "example.com",
+		SerialNumberAnnotation: "3a:7f:01",
+		NotAfterAnnotation:     "2020-08-01T12:00:00Z",
+	}, secret.Metadata.Annotations)
+
+	crt, err := base64.StdEncoding.DecodeString(secret.Data["tls.crt"])
+	assert.Nil(t, err)
+	assert.Equal(t, string(c.Certificate)+"issuer\n", string(crt))
+	key, err := base64.StdEncoding.DecodeString(secret.Data["tls.key"])
+	assert.Nil(t, err)
+	assert.Equal(t, "key\n", string(key))
+
+	t.Run("Test invalid names", func(t *testing.T) {
+		_, err := KubernetesSecret(c, "*.example.com", "web")
+		assert.NotNil(t, err)
+		_, err = KubernetesSecret(c, "example-tls", "web.prod")
+		assert.NotNil(t, err)
+	})
+	t.Run("Test missing private key", func(t *testing.T) {
+		noKey := *c
+		noKey.PrivateKey = []byte{}
+		_, err := KubernetesSecret(&noKey, "example-tls", "web")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,95 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewKey(t *testing.T) {
+	for _, keyType := range KeyTypes {
+		t.Run("Test with key type "+keyType, func(t *testing.T) {
+			key, err := NewKey(keyType, 0)
+			assert.Nil(t, err)
+
+			encoded, err := EncodePrivateKey(key)
+			assert.Nil(t, err)
+
+			block, _ := pem.Decode(encoded)
+			if block == nil {
+				t.Fatal("private key is not PEM encoded")
+			}
+			_, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			assert.Nil(t, err)
+		})
+	}
+
+	t.Run("Test with invalid key type", func(t *testing.T) {
+		_, err := NewKey("dsa", 0)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test with invalid EC size", func(t *testing.T) {
+		_, err := NewKey("ec", 1024)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestNewCSR(t *testing.T) {
+	key, err := NewKey("ec", 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr, err := NewCSR(key, "host.lab", []string{"alt.lab"})
+	assert.Nil(t, err)
+
+	block, _ := pem.Decode(csr)
+	if block == nil {
+		t.Fatal("CSR is not PEM encoded")
+	}
+	parsed, err := x509.ParseCertificateRequest(block.Bytes)
+	assert.Nil(t, err)
+	assert.Nil(t, parsed.CheckSignature())
+	assert.Equal(t, "host.lab", parsed.Subject.CommonName)
+	assert.Equal(t, []string{"host.lab", "alt.lab"}, parsed.DNSNames)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := NewKey("rsa", 0)
+	assert.Nil(t, err)
+	ecKey, err := NewKey("ec", 0)
+	assert.Nil(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey.(*ecdsa.PrivateKey))
+	assert.Nil(t, err)
+	pkcs8, err := EncodePrivateKey(ecKey)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+		key  crypto.Signer
+	}{
+		{"PKCS #1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey.(*rsa.PrivateKey))}), rsaKey},
+		{"SEC 1", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}), ecKey},
+		{"PKCS #8", pkcs8, ecKey},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			key, err := ParsePrivateKey(test.data)
+			assert.Nil(t, err)
+			assert.Equal(t, test.key.Public(), key.Public())
+		})
+	}
+
+	t.Run("Test invalid key", func(t *testing.T) {
+		_, err := ParsePrivateKey([]byte("key"))
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,605 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf16"
+)
+
+// The PKCS #12 files written by EncodePKCS12 use the same algorithms as OpenSSL's defaults before 3.0: the certificates
+// and private key are encrypted with pbeWithSHAAnd3-KeyTripleDES-CBC and the file is authenticated with HMAC-SHA1. These
+// are understood by every appliance, Java version, and Windows release which reads PKCS #12.
+//
+// The encoder is written here because golang.org/x/crypto/pkcs12 can only decode, and software.sslmate.com/src/go-pkcs12
+// can't give the private key a friendly name, which Java uses as the key's alias. It also requires a much newer
+// x/crypto than this module uses. The tests check the files are read by OpenSSL as well as x/crypto.
+const (
+	pkcs12Iterations = 2048
+	pkcs12SaltSize   = 8
+)
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidPKCS8ShroudedKeyBag      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCertTypeX509             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBEWithSHAAnd3KeyDESCBC  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidSHA1                     = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// The ASN.1 structures of a PKCS #12 file (RFC 7292).
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID     asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+// EncodePKCS12 returns a password protected PKCS #12 file (.p12 or .pfx) holding the given private key, certificate, and
+// issuer chain. The certificate and private key are given the friendly name, which is used as the alias of the key when
+// the file is loaded as a Java keystore. The issuer certificates are named after their common names.
+func EncodePKCS12(key interface{}, certificate *x509.Certificate, chain []*x509.Certificate, friendlyName string,
+	password string) ([]byte, error) {
+	encodedPassword, err := bmpString(password, true)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// The local key ID links the private key to its certificate
+	keyID := sha1.Sum(certificate.Raw)
+	attributes, err := bagAttributes(friendlyName, keyID[:])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	certBags := []safeBag{}
+	for i, c := range append([]*x509.Certificate{certificate}, chain...) {
+		bag, err := newCertBag(c)
+		if err != nil {
+			return []byte{}, err
+		}
+		if i == 0 {
+			bag.Attributes = attributes
+		} else if c.Subject.CommonName != "" {
+			if bag.Attributes, err = bagAttributes(c.Subject.CommonName, nil); err != nil {
+				return []byte{}, err
+			}
+		}
+		certBags = append(certBags, bag)
+	}
+
+	keyBag, err := newShroudedKeyBag(key, encodedPassword)
+	if err != nil {
+		return []byte{}, err
+	}
+	keyBag.Attributes = attributes
+
+	certs, err := newEncryptedContentInfo(certBags, encodedPassword)
+	if err != nil {
+		return []byte{}, err
+	}
+	keys, err := newDataContentInfo([]safeBag{keyBag})
+	if err != nil {
+		return []byte{}, err
+	}
+
+	authenticatedSafe, err := asn1.Marshal([]contentInfo{certs, keys})
+	if err != nil {
+		return []byte{}, err
+	}
+	authSafe, err := newDataContentInfoFromBytes(authenticatedSafe)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	mac, err := newMacData(authenticatedSafe, encodedPassword)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return asn1.Marshal(pfxPdu{Version: 3, AuthSafe: authSafe, MacData: mac})
+}
+
+// pkcs12Contents is the contents of a PKCS #12 file written by EncodePKCS12.
+type pkcs12Contents struct {
+	// Key is the DER encoded PKCS #8 private key.
+	Key []byte
+
+	// KeyName is the friendly name of the private key.
+	KeyName string
+
+	// Certificates are the DER encoded certificates, starting with the certificate of the private key.
+	Certificates [][]byte
+
+	// CertificateName is the friendly name of the first certificate.
+	CertificateName string
+}
+
+// decodePKCS12 decrypts the given PKCS #12 file with the given password, returning the private key and certificates it
+// holds without parsing them. Unlike golang.org/x/crypto/pkcs12 it works for every key type, but only the algorithms
+// used by EncodePKCS12 are understood.
+func decodePKCS12(data []byte, password string) (pkcs12Contents, error) {
+	encodedPassword, err := bmpString(password, true)
+	if err != nil {
+		return pkcs12Contents{}, err
+	}
+
+	var pfx pfxPdu
+	if err := unmarshalAll(data, &pfx); err != nil {
+		return pkcs12Contents{}, fmt.Errorf("unable to parse PKCS #12 file: %w", err)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return pkcs12Contents{}, errors.New("the PKCS #12 file isn't password integrity protected")
+	}
+
+	var authenticatedSafe []byte
+	if err := unmarshalAll(pfx.AuthSafe.Content.Bytes, &authenticatedSafe); err != nil {
+		return pkcs12Contents{}, err
+	}
+	if err := verifyMac(pfx.MacData, authenticatedSafe, encodedPassword); err != nil {
+		return pkcs12Contents{}, err
+	}
+
+	var infos []contentInfo
+	if err := unmarshalAll(authenticatedSafe, &infos); err != nil {
+		return pkcs12Contents{}, err
+	}
+
+	var contents pkcs12Contents
+	var keys int
+	for _, info := range infos {
+		bags, err := decodeSafeContents(info, encodedPassword)
+		if err != nil {
+			return pkcs12Contents{}, err
+		}
+
+		for _, bag := range bags {
+			name, err := bagFriendlyName(bag)
+			if err != nil {
+				return pkcs12Contents{}, err
+			}
+
+			switch {
+			case bag.ID.Equal(oidCertBag):
+				var c certBag
+				if err := unmarshalAll(bag.Value.Bytes, &c); err != nil {
+					return pkcs12Contents{}, err
+				}
+				if len(contents.Certificates) == 0 {
+					contents.CertificateName = name
+				}
+				contents.Certificates = append(contents.Certificates, c.Data)
+			case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+				var info encryptedPrivateKeyInfo
+				if err := unmarshalAll(bag.Value.Bytes, &info); err != nil {
+					return pkcs12Contents{}, err
+				}
+				if contents.Key, err = pbDecrypt(info.Algorithm, info.EncryptedData, encodedPassword); err != nil {
+					return pkcs12Contents{}, err
+				}
+				contents.KeyName = name
+				keys++
+			default:
+				return pkcs12Contents{}, fmt.Errorf("unsupported PKCS #12 safe bag: %s", bag.ID)
+			}
+		}
+	}
+
+	if keys != 1 {
+		return pkcs12Contents{}, fmt.Errorf("expected a single private key in the PKCS #12 file, found %d", keys)
+	}
+	return contents, nil
+}
+
+// decodeSafeContents returns the safe bags held by the given content info, decrypting them with the given password if
+// they're encrypted.
+func decodeSafeContents(info contentInfo, password []byte) ([]safeBag, error) {
+	var data []byte
+	switch {
+	case info.ContentType.Equal(oidDataContentType):
+		if err := unmarshalAll(info.Content.Bytes, &data); err != nil {
+			return nil, err
+		}
+	case info.ContentType.Equal(oidEncryptedDataContentType):
+		var encrypted encryptedData
+		if err := unmarshalAll(info.Content.Bytes, &encrypted); err != nil {
+			return nil, err
+		}
+		var err error
+		data, err = pbDecrypt(encrypted.EncryptedContentInfo.ContentEncryptionAlgorithm,
+			encrypted.EncryptedContentInfo.EncryptedContent, password)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PKCS #12 content type: %s", info.ContentType)
+	}
+
+	var bags []safeBag
+	if err := unmarshalAll(data, &bags); err != nil {
+		return nil, err
+	}
+	return bags, nil
+}
+
+// bagFriendlyName returns the friendly name attribute of the given safe bag, or an empty string if it has none.
+func bagFriendlyName(bag safeBag) (string, error) {
+	for _, attribute := range bag.Attributes {
+		if !attribute.ID.Equal(oidFriendlyName) || len(attribute.Values) != 1 {
+			continue
+		}
+
+		var value asn1.RawValue
+		if err := unmarshalAll(attribute.Values[0].FullBytes, &value); err != nil {
+			return "", err
+		}
+		if value.Tag != asn1.TagBMPString || len(value.Bytes)%2 != 0 {
+			return "", errors.New("the PKCS #12 friendly name isn't a BMPString")
+		}
+
+		units := make([]uint16, len(value.Bytes)/2)
+		for i := range units {
+			units[i] = uint16(value.Bytes[2*i])<<8 | uint16(value.Bytes[2*i+1])
+		}
+		return string(utf16.Decode(units)), nil
+	}
+	return "", nil
+}
+
+// verifyMac returns an error unless the given MAC data holds the HMAC-SHA1 of the given authenticated safe keyed with
+// the given password.
+func verifyMac(mac macData, authenticatedSafe []byte, password []byte) error {
+	if !mac.Mac.Algorithm.Algorithm.Equal(oidSHA1) {
+		return fmt.Errorf("unsupported PKCS #12 MAC algorithm: %s", mac.Mac.Algorithm.Algorithm)
+	}
+
+	h := hmac.New(sha1.New, pbkdf(mac.MacSalt, password, mac.Iterations, 3, sha1.Size))
+	h.Write(authenticatedSafe)
+	if !hmac.Equal(h.Sum(nil), mac.Mac.Digest) {
+		return errors.New("incorrect PKCS #12 password")
+	}
+	return nil
+}
+
+// pbDecrypt decrypts the given data encrypted with pbeWithSHAAnd3-KeyTripleDES-CBC by pbEncrypt using the given
+// password.
+func pbDecrypt(algorithm pkix.AlgorithmIdentifier, encrypted []byte, password []byte) ([]byte, error) {
+	if !algorithm.Algorithm.Equal(oidPBEWithSHAAnd3KeyDESCBC) {
+		return nil, fmt.Errorf("unsupported PKCS #12 encryption algorithm: %s", algorithm.Algorithm)
+	}
+
+	var params pbeParams
+	if err := unmarshalAll(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+
+	block, err := des.NewTripleDESCipher(pbkdf(params.Salt, password, params.Iterations, 1, 24))
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) == 0 || len(encrypted)%block.BlockSize() != 0 {
+		return nil, errors.New("the PKCS #12 encrypted data isn't a multiple of the block size")
+	}
+	iv := pbkdf(params.Salt, password, params.Iterations, 2, block.BlockSize())
+
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted)
+
+	// PKCS #7 padding
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > block.BlockSize() ||
+		!bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("invalid PKCS #12 padding")
+	}
+	return decrypted[:len(decrypted)-padding], nil
+}
+
+// unmarshalAll parses the given DER encoded value into the given value, returning an error if any data follows it.
+func unmarshalAll(der []byte, value interface{}) error {
+	rest, err := asn1.Unmarshal(der, value)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("trailing data after ASN.1 value")
+	}
+	return nil
+}
+
+// newCertBag returns a safe bag holding the given certificate.
+func newCertBag(c *x509.Certificate) (safeBag, error) {
+	data, err := asn1.Marshal(certBag{ID: oidCertTypeX509, Data: c.Raw})
+	if err != nil {
+		return safeBag{}, err
+	}
+	return safeBag{ID: oidCertBag, Value: explicit(data)}, nil
+}
+
+// newShroudedKeyBag returns a safe bag holding the given private key in PKCS #8 form, encrypted with the given password.
+func newShroudedKeyBag(key interface{}, password []byte) (safeBag, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	algorithm, encrypted, err := pbEncrypt(der, password)
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	data, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: encrypted})
+	if err != nil {
+		return safeBag{}, err
+	}
+	return safeBag{ID: oidPKCS8ShroudedKeyBag, Value: explicit(data)}, nil
+}
+
+// bagAttributes returns the friendlyName and localKeyId attributes of a safe bag. Either may be empty to omit it.
+func bagAttributes(friendlyName string, keyID []byte) ([]pkcs12Attribute, error) {
+	var attributes []pkcs12Attribute
+	if friendlyName != "" {
+		name, err := bmpString(friendlyName, false)
+		if err != nil {
+			return nil, err
+		}
+		value, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: name})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, pkcs12Attribute{ID: oidFriendlyName, Values: []asn1.RawValue{{FullBytes: value}}})
+	}
+
+	if len(keyID) > 0 {
+		value, err := asn1.Marshal(keyID)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, pkcs12Attribute{ID: oidLocalKeyID, Values: []asn1.RawValue{{FullBytes: value}}})
+	}
+
+	return attributes, nil
+}
+
+// newDataContentInfo returns a content info holding the given safe bags unencrypted.
+func newDataContentInfo(bags []safeBag) (contentInfo, error) {
+	data, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return newDataContentInfoFromBytes(data)
+}
+
+// newDataContentInfoFromBytes returns a content info holding the given data as an octet string.
+func newDataContentInfoFromBytes(data []byte) (contentInfo, error) {
+	content, err := asn1.Marshal(data)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidDataContentType, Content: explicit(content)}, nil
+}
+
+// newEncryptedContentInfo returns a content info holding the given safe bags encrypted with the given password.
+func newEncryptedContentInfo(bags []safeBag, password []byte) (contentInfo, error) {
+	data, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	algorithm, encrypted, err := pbEncrypt(data, password)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	content, err := asn1.Marshal(encryptedData{
+		Version: 0,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidDataContentType,
+			ContentEncryptionAlgorithm: algorithm,
+			EncryptedContent:           encrypted,
+		},
+	})
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidEncryptedDataContentType, Content: explicit(content)}, nil
+}
+
+// newMacData returns the HMAC-SHA1 of the given authenticated safe keyed with the given password.
+func newMacData(authenticatedSafe []byte, password []byte) (macData, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return macData{}, err
+	}
+
+	key := pbkdf(salt, password, pkcs12Iterations, 3, sha1.Size)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(authenticatedSafe)
+
+	return macData{
+		Mac: digestInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+			Digest:    mac.Sum(nil),
+		},
+		MacSalt:    salt,
+		Iterations: pkcs12Iterations,
+	}, nil
+}
+
+// pbEncrypt encrypts the given data with pbeWithSHAAnd3-KeyTripleDES-CBC using the given password, returning the
+// algorithm identifier (including the salt and iterations) along with the encrypted data.
+func pbEncrypt(data []byte, password []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, []byte{}, err
+	}
+
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: pkcs12Iterations})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, []byte{}, err
+	}
+	algorithm := pkix.AlgorithmIdentifier{Algorithm: oidPBEWithSHAAnd3KeyDESCBC, Parameters: asn1.RawValue{FullBytes: params}}
+
+	block, err := des.NewTripleDESCipher(pbkdf(salt, password, pkcs12Iterations, 1, 24))
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, []byte{}, err
+	}
+	iv := pbkdf(salt, password, pkcs12Iterations, 2, block.BlockSize())
+
+	// PKCS #7 padding
+	padding := block.BlockSize() - len(data)%block.BlockSize()
+	encrypted := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	return algorithm, encrypted, nil
+}
+
+// pbkdf derives a key of the given size from the given salt and password using the PKCS #12 key derivation function
+// with SHA-1 (RFC 7292 appendix B.2). The ID is 1 for encryption keys, 2 for IVs, and 3 for MAC keys.
+func pbkdf(salt []byte, password []byte, iterations int, id byte, size int) []byte {
+	const u = sha1.Size // Hash output size
+	const v = 64        // Hash block size
+
+	d := bytes.Repeat([]byte{id}, v)
+	s := fill(salt, v)
+	p := fill(password, v)
+	i := append(s, p...)
+
+	one := big.NewInt(1)
+	var a []byte
+	for len(a) < size {
+		hash := sha1.Sum(append(append([]byte{}, d...), i...))
+		ai := hash[:]
+		for r := 1; r < iterations; r++ {
+			hash = sha1.Sum(ai)
+			ai = hash[:]
+		}
+		a = append(a, ai...)
+
+		// Each block of I is incremented by B + 1, where B is Ai repeated to the block size
+		b := new(big.Int).SetBytes(fill(ai, v))
+		b.Add(b, one)
+		for j := 0; j < len(i); j += v {
+			sum := new(big.Int).SetBytes(i[j : j+v])
+			sum.Add(sum, b)
+			block := sum.Bytes()
+			if len(block) > v {
+				block = block[len(block)-v:]
+			}
+			copy(i[j:j+v], make([]byte, v))
+			copy(i[j+v-len(block):j+v], block)
+		}
+	}
+
+	return a[:size]
+}
+
+// fill repeats the given data to fill a multiple of v bytes. Empty data stays empty.
+func fill(data []byte, v int) []byte {
+	if len(data) == 0 {
+		return []byte{}
+	}
+
+	n := v * ((len(data) + v - 1) / v)
+	filled := make([]byte, n)
+	for i := range filled {
+		filled[i] = data[i%len(data)]
+	}
+	return filled
+}
+
+// bmpString returns the given string encoded as a big endian UCS-2 BMPString, with a zero terminator if terminate is
+// true (as is used for passwords).
+func bmpString(s string, terminate bool) ([]byte, error) {
+	encoded := make([]byte, 0, 2*len(s)+2)
+	for _, r := range s {
+		if r1, _ := utf16.EncodeRune(r); r1 != 0xfffd {
+			return []byte{}, errors.New("the string contains characters outside the Basic Multilingual Plane")
+		}
+		encoded = append(encoded, byte(r>>8), byte(r))
+	}
+
+	if terminate {
+		encoded = append(encoded, 0, 0)
+	}
+	return encoded, nil
+}
+
+// explicit returns the given DER encoded value with an explicit [0] tag. Raw values are marshalled as-is, so the tag
+// can't be given as a struct tag.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// newSalt returns a random salt.
+func newSalt() ([]byte, error) {
+	salt := make([]byte, pkcs12SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return []byte{}, fmt.Errorf("unable to generate salt: %w", err)
+	}
+	return salt, nil
+}
@@ -0,0 +1,277 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pkcs12"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestChain returns a Certificate for the given domain with a private key of the given type, issued by an
+// intermediate named R3. Besides the types supported by NewKey, ed25519 is supported as gcert may store such keys.
+func newTestChain(t *testing.T, domain string, keyType string) *Certificate {
+	issue := func(template *x509.Certificate, parent *x509.Certificate, key crypto.Signer,
+		parentKey crypto.Signer) *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	caKey, err := NewKey("ec", 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "R3"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ca = issue(ca, ca, caKey, caKey)
+
+	var key crypto.Signer
+	if keyType == "ed25519" {
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		key, err = NewKey(keyType, 0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := issue(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}, ca, key, caKey)
+
+	keyPEM, err := EncodePrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Certificate{
+		Domain:            domain,
+		Certificate:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}),
+		PrivateKey:        keyPEM,
+		IssuerCertificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+	}
+}
+
+func TestEncodePKCS12(t *testing.T) {
+	for _, keyType := range []string{"rsa", "ec"} {
+		t.Run("Test "+keyType+" key", func(t *testing.T) {
+			c := newTestChain(t, "example.com", keyType)
+			certificate, err := c.X509()
+			assert.Nil(t, err)
+			chain, err := ParseCertificates(c.IssuerCertificate)
+			assert.Nil(t, err)
+			key, err := ParsePrivateKey(c.PrivateKey)
+			assert.Nil(t, err)
+
+			data, err := EncodePKCS12(key, certificate, chain, "web", "secret")
+			assert.Nil(t, err)
+
+			blocks, err := pkcs12.ToPEM(data, "secret")
+			assert.Nil(t, err)
+			assert.Len(t, blocks, 3)
+
+			var certificates []*pem.Block
+			var keys []*pem.Block
+			for _, block := range blocks {
+				if block.Type == "CERTIFICATE" {
+					certificates = append(certificates, block)
+				} else {
+					keys = append(keys, block)
+				}
+			}
+
+			assert.Len(t, certificates, 2)
+			assert.Equal(t, certificate.Raw, certificates[0].Bytes)
+			assert.Equal(t, "web", certificates[0].Headers["friendlyName"])
+			assert.Equal(t, chain[0].Raw, certificates[1].Bytes)
+			assert.Equal(t, "R3", certificates[1].Headers["friendlyName"])
+
+			assert.Len(t, keys, 1)
+			assert.Equal(t, "web", keys[0].Headers["friendlyName"])
+			assert.Equal(t, certificates[0].Headers["localKeyId"], keys[0].Headers["localKeyId"])
+
+			_, err = pkcs12.ToPEM(data, "wrong")
+			assert.Equal(t, pkcs12.ErrIncorrectPassword, err)
+		})
+	}
+	t.Run("Test decoding the key and certificate", func(t *testing.T) {
+		c := newTestChain(t, "example.com", "ec")
+		certificate, err := c.X509()
+		assert.Nil(t, err)
+		key, err := ParsePrivateKey(c.PrivateKey)
+		assert.Nil(t, err)
+
+		data, err := EncodePKCS12(key, certificate, nil, "", "")
+		assert.Nil(t, err)
+
+		decodedKey, decoded, err := pkcs12.Decode(data, "")
+		assert.Nil(t, err)
+		assert.Equal(t, certificate.Raw, decoded.Raw)
+		assert.Equal(t, key.Public(), decodedKey.(crypto.Signer).Public())
+	})
+}
+
+// TestEncodePKCS12_OpenSSL checks the files are read by OpenSSL, which most appliances use to import PKCS #12, rather
+// than only by the decoder the other tests use.
+func TestEncodePKCS12_OpenSSL(t *testing.T) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// OpenSSL writes the bag attributes and algorithms to stderr and the decrypted PEM blocks to stdout
+	run := func(path string, password string) (string, []byte, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(openssl, "pkcs12", "-in", path, "-passin", "pass:"+password, "-info", "-nodes")
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		return stdout.String() + stderr.String(), stdout.Bytes(), err
+	}
+
+	for _, keyType := range []string{"rsa", "ec"} {
+		t.Run("Test "+keyType+" key", func(t *testing.T) {
+			c := newTestChain(t, "example.com", keyType)
+			certificate, err := c.X509()
+			assert.Nil(t, err)
+			chain, err := ParseCertificates(c.IssuerCertificate)
+			assert.Nil(t, err)
+			key, err := ParsePrivateKey(c.PrivateKey)
+			assert.Nil(t, err)
+
+			data, err := EncodePKCS12(key, certificate, chain, "web", "secret")
+			assert.Nil(t, err)
+			path := filepath.Join(dir, keyType+".p12")
+			if err := ioutil.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			info, out, err := run(path, "secret")
+			if !assert.Nil(t, err, info) {
+				return
+			}
+			assert.Contains(t, info, "MAC: sha1")
+			assert.Contains(t, info, "pbeWithSHA1And3-KeyTripleDES-CBC")
+			assert.Contains(t, info, "friendlyName: web")
+			assert.Contains(t, info, "friendlyName: R3")
+
+			var certificates [][]byte
+			var decodedKey crypto.Signer
+			for block, rest := pem.Decode(out); block != nil; block, rest = pem.Decode(rest) {
+				switch block.Type {
+				case "CERTIFICATE":
+					certificates = append(certificates, block.Bytes)
+				case "PRIVATE KEY":
+					k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+					assert.Nil(t, err)
+					decodedKey = k.(crypto.Signer)
+				}
+			}
+			assert.Equal(t, [][]byte{certificate.Raw, chain[0].Raw}, certificates)
+			if assert.NotNil(t, decodedKey) {
+				assert.Equal(t, key.Public(), decodedKey.Public())
+			}
+
+			info, _, err = run(path, "wrong")
+			assert.NotNil(t, err)
+			assert.Contains(t, info, "Mac verify error")
+		})
+	}
+	t.Run("Test empty password", func(t *testing.T) {
+		c := newTestChain(t, "example.com", "ec")
+		certificate, err := c.X509()
+		assert.Nil(t, err)
+		key, err := ParsePrivateKey(c.PrivateKey)
+		assert.Nil(t, err)
+
+		data, err := EncodePKCS12(key, certificate, nil, "", "")
+		assert.Nil(t, err)
+		path := filepath.Join(dir, "empty.p12")
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		info, _, err := run(path, "")
+		assert.Nil(t, err, info)
+	})
+}
+
+func TestDecodePKCS12(t *testing.T) {
+	for _, keyType := range []string{"rsa", "ec", "ed25519"} {
+		t.Run("Test "+keyType+" key", func(t *testing.T) {
+			c := newTestChain(t, "example.com", keyType)
+			certificate, err := c.X509()
+			assert.Nil(t, err)
+			chain, err := ParseCertificates(c.IssuerCertificate)
+			assert.Nil(t, err)
+			key, err := ParsePrivateKey(c.PrivateKey)
+			assert.Nil(t, err)
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			assert.Nil(t, err)
+
+			data, err := EncodePKCS12(key, certificate, chain, "wëb", "secret")
+			assert.Nil(t, err)
+
+			contents, err := decodePKCS12(data, "secret")
+			assert.Nil(t, err)
+			assert.Equal(t, der, contents.Key)
+			assert.Equal(t, "wëb", contents.KeyName)
+			assert.Equal(t, [][]byte{certificate.Raw, chain[0].Raw}, contents.Certificates)
+			assert.Equal(t, "wëb", contents.CertificateName)
+
+			_, err = decodePKCS12(data, "wrong")
+			assert.NotNil(t, err)
+		})
+	}
+	t.Run("Test invalid data", func(t *testing.T) {
+		_, err := decodePKCS12([]byte("not a PKCS #12 file"), "")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPbkdf(t *testing.T) {
+	// Test vectors from the x/crypto/pkcs12 tests
+	password, err := bmpString("sesame", true)
+	assert.Nil(t, err)
+	key := pbkdf([]byte("\xff\xff\xff\xff\xff\xff\xff\xff"), password, 2048, 1, 24)
+	assert.Equal(t, []byte("\x7c\xd9\xfd\x3e\x2b\x3b\xe7\x69\x1a\x44\xe3\xbe\xf0\xf9\xea\x0f\xb9\xb8\x97\xd4\xe3\x25\xd9\xd1"),
+		key)
+
+	// The blocks of I end up with a leading zero byte
+	key = pbkdf([]byte("\xf3\x7e\x05\xb5\x18\x32\x4b\x4b"), []byte("\x00\x00"), 2048, 1, 24)
+	assert.Equal(t, []byte("\x00\xf7\x59\xff\x47\xd1\x4d\xd0\x36\x65\xd5\x94\x3c\xb3\xc4\xa3\x9a\x25\x55\xc0\x2a\xed\x66\xe1"),
+		key)
+}
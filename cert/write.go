@@ -0,0 +1,219 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"github.com/jmgilman/gcli/files"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// CertificateFile is the name of the file containing the PEM encoded certificate.
+	CertificateFile = "cert.pem"
+	// PrivateKeyFile is the name of the file containing the PEM encoded private key.
+	PrivateKeyFile = "privkey.pem"
+	// ChainFile is the name of the file containing the PEM encoded issuer certificate chain.
+	ChainFile = "chain.pem"
+	// FullChainFile is the name of the file containing the certificate followed by the issuer certificate chain.
+	FullChainFile = "fullchain.pem"
+	// CombinedFile is the name of the file containing the certificate, issuer certificate chain, and private key.
+	CombinedFile = "combined.pem"
+	// CertificateDERFile is the name of the file containing the DER encoded certificate.
+	CertificateDERFile = "cert.der"
+	// PrivateKeyDERFile is the name of the file containing the DER encoded PKCS #8 private key.
+	PrivateKeyDERFile = "privkey.der"
+	// PKCS12File is the name of the PKCS #12 file containing the certificate, issuer chain, and private key.
+	PKCS12File = "cert.p12"
+)
+
+// The formats a certificate can be written in.
+const (
+	// PEM writes the certificate, private key, issuer chain, and full chain to separate PEM files.
+	PEM = "pem"
+	// Combined writes the certificate, issuer chain, and private key to a single PEM file (i.e. for HAProxy).
+	Combined = "combined"
+	// DER writes the certificate and private key DER encoded.
+	DER = "der"
+	// PKCS12 writes the certificate, issuer chain, and private key to a password protected PKCS #12 file.
+	PKCS12 = "p12"
+)
+
+// Formats contains every format a certificate can be written in.
+var Formats = []string{PEM, Combined, DER, PKCS12}
+
+// WriteOptions configures how WriteFormat writes a certificate.
+type WriteOptions struct {
+	// Format is the format the certificate is written in. It defaults to PEM.
+	Format string
+
+	// Password protects the PKCS #12 file.
+	Password string
+
+	// FriendlyName is the name given to the certificate and private key in the PKCS #12 file, which Java uses as the
+	// alias of the key. It defaults to the domain of the certificate.
+	FriendlyName string
+}
+
+// output is a single file written for a certificate.
+type output struct {
+	name string
+	data []byte
+	mode os.FileMode
+}
+
+// Write atomically writes the certificate, private key, issuer chain, and full chain of the given Certificate to the
+// given directory, creating it if necessary. The private key is only readable by the current user and any part of the
+// Certificate which is empty is skipped. It returns the paths of every file whose contents changed.
+func Write(dir string, c *Certificate) ([]string, error) {
+	return WriteFormat(dir, c, WriteOptions{Format: PEM})
+}
+
+// WriteFormat atomically writes the given Certificate to the given directory in the format given by the options,
+// creating the directory if necessary. Files containing the private key are only readable by the current user. It
+// returns the paths of every file whose contents changed. As PKCS #12 files are encrypted with a random salt, an
+// existing PKCS #12 file is only replaced if it doesn't hold the same private key and certificates under the same
+// password and friendly name.
+func WriteFormat(dir string, c *Certificate, opts WriteOptions) ([]string, error) {
+	outputs, err := formatOutputs(dir, c, opts)
+	if err != nil {
+		return []string{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return []string{}, err
+	}
+
+	var changed []string
+	for _, output := range outputs {
+		if len(output.data) == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, output.name)
+		written, err := files.WriteAtomic(path, output.data, output.mode)
+		if err != nil {
+			return changed, err
+		}
+		if written {
+			changed = append(changed, path)
+		}
+	}
+
+	return changed, nil
+}
+
+// formatOutputs returns the files written for the given Certificate in the format given by the options.
+func formatOutputs(dir string, c *Certificate, opts WriteOptions) ([]output, error) {
+	switch opts.Format {
+	case "", PEM:
+		return []output{
+			{CertificateFile, c.Certificate, 0644},
+			{PrivateKeyFile, c.PrivateKey, 0600},
+			{ChainFile, c.IssuerCertificate, 0644},
+			{FullChainFile, append(append([]byte{}, c.Certificate...), c.IssuerCertificate...), 0644},
+		}, nil
+	case Combined:
+		data := append(append(append([]byte{}, c.Certificate...), c.IssuerCertificate...), c.PrivateKey...)
+		return []output{{CombinedFile, data, 0600}}, nil
+	case DER:
+		return derOutputs(c)
+	case PKCS12:
+		return pkcs12Outputs(dir, c, opts)
+	default:
+		return nil, fmt.Errorf("unknown certificate format %q (expected one of %s)", opts.Format,
+			strings.Join(Formats, ", "))
+	}
+}
+
+// derOutputs returns the DER encoded certificate and PKCS #8 private key of the given Certificate.
+func derOutputs(c *Certificate) ([]output, error) {
+	certificate, err := c.X509()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate for %s: %w", c.Domain, err)
+	}
+	outputs := []output{{CertificateDERFile, certificate.Raw, 0644}}
+
+	if len(c.PrivateKey) > 0 {
+		key, err := ParsePrivateKey(c.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse private key for %s: %w", c.Domain, err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output{PrivateKeyDERFile, der, 0600})
+	}
+
+	return outputs, nil
+}
+
+// pkcs12Outputs returns the PKCS #12 file of the given Certificate, or nothing if the existing file in the given
+// directory already holds it.
+func pkcs12Outputs(dir string, c *Certificate, opts WriteOptions) ([]output, error) {
+	certificate, err := c.X509()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate for %s: %w", c.Domain, err)
+	}
+	chain, err := ParseCertificates(c.IssuerCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse issuer chain for %s: %w", c.Domain, err)
+	}
+	if len(c.PrivateKey) == 0 {
+		return nil, fmt.Errorf("the certificate for %s has no private key", c.Domain)
+	}
+	key, err := ParsePrivateKey(c.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key for %s: %w", c.Domain, err)
+	}
+
+	friendlyName := opts.FriendlyName
+	if friendlyName == "" {
+		friendlyName = c.Domain
+	}
+
+	path := filepath.Join(dir, PKCS12File)
+	if existing, err := ioutil.ReadFile(path); err == nil {
+		if pkcs12Holds(existing, opts.Password, friendlyName, key, append([]*x509.Certificate{certificate}, chain...)) {
+			return []output{}, nil
+		}
+	}
+
+	data, err := EncodePKCS12(key, certificate, chain, friendlyName, opts.Password)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode PKCS #12 file for %s: %w", c.Domain, err)
+	}
+	return []output{{PKCS12File, data, 0600}}, nil
+}
+
+// pkcs12Holds returns true if the given PKCS #12 file can be decrypted with the given password and holds exactly the
+// given private key and certificates, the first of which has the given friendly name along with the key. The key
+// material is compared directly rather than parsed, so every key type ParsePrivateKey supports is handled.
+func pkcs12Holds(data []byte, password string, friendlyName string, key crypto.Signer,
+	certificates []*x509.Certificate) bool {
+	held, err := decodePKCS12(data, password)
+	if err != nil {
+		return false
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil || !bytes.Equal(held.Key, der) {
+		return false
+	}
+	if held.KeyName != friendlyName || held.CertificateName != friendlyName ||
+		len(held.Certificates) != len(certificates) {
+		return false
+	}
+
+	for i, c := range held.Certificates {
+		if !bytes.Equal(c, certificates[i].Raw) {
+			return false
+		}
+	}
+	return true
+}
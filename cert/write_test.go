@@ -0,0 +1,161 @@
+package cert
+
+import (
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pkcs12"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Certificate{
+		Domain:            "test.example.com",
+		Certificate:       []byte("cert\n"),
+		PrivateKey:        []byte("key\n"),
+		IssuerCertificate: []byte("issuer\n"),
+	}
+
+	t.Run("Test writing a new certificate", func(t *testing.T) {
+		changed, err := Write(filepath.Join(dir, "test"), c)
+		assert.Nil(t, err)
+		assert.Len(t, changed, 4)
+
+		fullChain, err := ioutil.ReadFile(filepath.Join(dir, "test", FullChainFile))
+		assert.Nil(t, err)
+		assert.Equal(t, "cert\nissuer\n", string(fullChain))
+
+		info, err := os.Stat(filepath.Join(dir, "test", PrivateKeyFile))
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("Test writing an unchanged certificate", func(t *testing.T) {
+		changed, err := Write(filepath.Join(dir, "test"), c)
+		assert.Nil(t, err)
+		assert.Empty(t, changed)
+	})
+
+	t.Run("Test writing a certificate without a private key", func(t *testing.T) {
+		noKey := *c
+		noKey.PrivateKey = []byte{}
+		changed, err := Write(filepath.Join(dir, "nokey"), &noKey)
+		assert.Nil(t, err)
+		assert.Len(t, changed, 3)
+		assert.NoFileExists(t, filepath.Join(dir, "nokey", PrivateKeyFile))
+	})
+}
+
+func TestWriteFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestChain(t, "example.com", "ec")
+
+	t.Run("Test combined", func(t *testing.T) {
+		changed, err := WriteFormat(filepath.Join(dir, "combined"), c, WriteOptions{Format: Combined})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "combined", CombinedFile)}, changed)
+
+		data, err := ioutil.ReadFile(changed[0])
+		assert.Nil(t, err)
+		assert.Equal(t, string(c.Certificate)+string(c.IssuerCertificate)+string(c.PrivateKey), string(data))
+
+		info, err := os.Stat(changed[0])
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+	t.Run("Test DER", func(t *testing.T) {
+		changed, err := WriteFormat(filepath.Join(dir, "der"), c, WriteOptions{Format: DER})
+		assert.Nil(t, err)
+		assert.Len(t, changed, 2)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "der", CertificateDERFile))
+		assert.Nil(t, err)
+		certificate, err := c.X509()
+		assert.Nil(t, err)
+		assert.Equal(t, certificate.Raw, data)
+
+		data, err = ioutil.ReadFile(filepath.Join(dir, "der", PrivateKeyDERFile))
+		assert.Nil(t, err)
+		_, err = x509.ParsePKCS8PrivateKey(data)
+		assert.Nil(t, err)
+	})
+	t.Run("Test PKCS #12", func(t *testing.T) {
+		opts := WriteOptions{Format: PKCS12, Password: "secret"}
+		path := filepath.Join(dir, "p12", PKCS12File)
+
+		changed, err := WriteFormat(filepath.Join(dir, "p12"), c, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{path}, changed)
+
+		data, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		blocks, err := pkcs12.ToPEM(data, "secret")
+		assert.Nil(t, err)
+		assert.Equal(t, "example.com", blocks[0].Headers["friendlyName"])
+
+		// The same certificate isn't written again even though the encoding differs
+		changed, err = WriteFormat(filepath.Join(dir, "p12"), c, opts)
+		assert.Nil(t, err)
+		assert.Empty(t, changed)
+
+		opts.FriendlyName = "tomcat"
+		changed, err = WriteFormat(filepath.Join(dir, "p12"), c, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{path}, changed)
+
+		opts.Password = "changed"
+		changed, err = WriteFormat(filepath.Join(dir, "p12"), c, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{path}, changed)
+	})
+	t.Run("Test PKCS #12 with an ed25519 key", func(t *testing.T) {
+		ed25519Chain := newTestChain(t, "example.com", "ed25519")
+		opts := WriteOptions{Format: PKCS12, Password: "secret"}
+		path := filepath.Join(dir, "ed25519", PKCS12File)
+
+		changed, err := WriteFormat(filepath.Join(dir, "ed25519"), ed25519Chain, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{path}, changed)
+
+		changed, err = WriteFormat(filepath.Join(dir, "ed25519"), ed25519Chain, opts)
+		assert.Nil(t, err)
+		assert.Empty(t, changed)
+	})
+	t.Run("Test PKCS #12 with a changed private key", func(t *testing.T) {
+		opts := WriteOptions{Format: PKCS12, Password: "secret"}
+		path := filepath.Join(dir, "rekeyed", PKCS12File)
+
+		_, err := WriteFormat(filepath.Join(dir, "rekeyed"), c, opts)
+		assert.Nil(t, err)
+
+		rekeyed := *c
+		rekeyed.PrivateKey = newTestChain(t, "example.com", "ec").PrivateKey
+		changed, err := WriteFormat(filepath.Join(dir, "rekeyed"), &rekeyed, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{path}, changed)
+	})
+	t.Run("Test PKCS #12 without a private key", func(t *testing.T) {
+		noKey := *c
+		noKey.PrivateKey = []byte{}
+		_, err := WriteFormat(filepath.Join(dir, "nokey"), &noKey, WriteOptions{Format: PKCS12})
+		assert.NotNil(t, err)
+	})
+	t.Run("Test unknown format", func(t *testing.T) {
+		_, err := WriteFormat(filepath.Join(dir, "unknown"), c, WriteOptions{Format: "jks"})
+		assert.NotNil(t, err)
+		assert.NoDirExists(t, filepath.Join(dir, "unknown"))
+	})
+}
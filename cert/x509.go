@@ -0,0 +1,96 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// The Let's Encrypt environments a certificate can be issued from.
+const (
+	Staging    = "staging"
+	Production = "production"
+	UnknownCA  = "unknown"
+)
+
+// stagingIssuers are parts of the common names of the intermediates used by the Let's Encrypt staging environment
+// (i.e. "Fake LE Intermediate X1" or "(STAGING) Pretend Pear X1").
+var stagingIssuers = []string{"fake le", "(staging)"}
+
+// X509 parses the PEM encoded certificate of the Certificate.
+func (c *Certificate) X509() (*x509.Certificate, error) {
+	return ParseCertificate(c.Certificate)
+}
+
+// ParseCertificate parses the first certificate in the given PEM encoded data.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return &x509.Certificate{}, fmt.Errorf("no PEM encoded certificate was found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ParseCertificates parses every PEM encoded certificate in the given data, i.e. an issuer chain.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certificates, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return []*x509.Certificate{}, err
+		}
+		certificates = append(certificates, c)
+	}
+}
+
+// FormatSerial formats a certificate serial number the same way Vault does (colon separated hex bytes).
+func FormatSerial(serial *big.Int) string {
+	bytes := serial.Bytes()
+	parts := make([]string, len(bytes))
+	for i, b := range bytes {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// Environment returns which Let's Encrypt environment (Staging or Production) issued the given certificate, or
+// UnknownCA if it wasn't issued by Let's Encrypt.
+func Environment(c *x509.Certificate) string {
+	issuer := strings.ToLower(c.Issuer.CommonName)
+	for _, staging := range stagingIssuers {
+		if strings.Contains(issuer, staging) {
+			return Staging
+		}
+	}
+
+	for _, org := range c.Issuer.Organization {
+		if org == "Let's Encrypt" {
+			return Production
+		}
+	}
+	return UnknownCA
+}
+
+// DaysRemaining returns the number of whole days from the given time until the given certificate expires. It's negative
+// once the certificate has expired.
+func DaysRemaining(c *x509.Certificate, now time.Time) int {
+	remaining := c.NotAfter.Sub(now)
+	days := int(remaining / (24 * time.Hour))
+	if remaining < 0 && remaining%(24*time.Hour) != 0 {
+		days--
+	}
+	return days
+}
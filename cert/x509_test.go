@@ -0,0 +1,109 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a PEM encoded certificate for the given domain issued by a CA with the given name.
+func newTestCertificate(t *testing.T, domain string, issuer pkix.Name, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: issuer}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x3a7f01),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain, "www." + domain},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertificate_X509(t *testing.T) {
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	data := newData()
+	data["certificate"] = base64.StdEncoding.EncodeToString(
+		newTestCertificate(t, "example.com", pkix.Name{CommonName: "R3"}, notAfter))
+
+	c, err := NewCertificateFromData("example.com", data)
+	assert.Nil(t, err)
+	assert.Equal(t, "3a:7f:01", c.SerialNumber)
+
+	parsed, err := c.X509()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, parsed.DNSNames)
+	assert.True(t, notAfter.Equal(parsed.NotAfter))
+
+	_, err = (&Certificate{Certificate: []byte("certificate")}).X509()
+	assert.NotNil(t, err)
+}
+
+func TestParseCertificates(t *testing.T) {
+	first := newTestCertificate(t, "example.com", pkix.Name{CommonName: "R3"}, time.Now())
+	second := newTestCertificate(t, "example.org", pkix.Name{CommonName: "R3"}, time.Now())
+
+	certificates, err := ParseCertificates(append(append([]byte{}, first...), second...))
+	assert.Nil(t, err)
+	assert.Len(t, certificates, 2)
+	assert.Equal(t, "example.com", certificates[0].Subject.CommonName)
+	assert.Equal(t, "example.org", certificates[1].Subject.CommonName)
+
+	certificates, err = ParseCertificates([]byte{})
+	assert.Nil(t, err)
+	assert.Empty(t, certificates)
+}
+
+func TestEnvironment(t *testing.T) {
+	tests := []struct {
+		issuer pkix.Name
+		env    string
+	}{
+		{pkix.Name{CommonName: "Fake LE Intermediate X1"}, Staging},
+		{pkix.Name{CommonName: "(STAGING) Pretend Pear X1", Organization: []string{"(STAGING) Let's Encrypt"}}, Staging},
+		{pkix.Name{CommonName: "R3", Organization: []string{"Let's Encrypt"}}, Production},
+		{pkix.Name{CommonName: "Lab CA", Organization: []string{"Lab"}}, UnknownCA},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.issuer.CommonName, func(t *testing.T) {
+			parsed, err := ParseCertificate(newTestCertificate(t, "example.com", test.issuer, time.Now()))
+			assert.Nil(t, err)
+			assert.Equal(t, test.env, Environment(parsed))
+		})
+	}
+}
+
+func TestDaysRemaining(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		notAfter time.Time
+		days     int
+	}{
+		{now.Add(30*24*time.Hour + time.Hour), 30},
+		{now.Add(time.Hour), 0},
+		{now.Add(-time.Hour), -1},
+		{now.Add(-48 * time.Hour), -2},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.days, DaysRemaining(&x509.Certificate{NotAfter: test.notAfter}, now))
+	}
+}
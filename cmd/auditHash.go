@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/audit"
+
+	"github.com/spf13/cobra"
+)
+
+// auditHashCmd represents the hash command of the audit command
+var auditHashCmd = &cobra.Command{
+	Use:   "hash [value]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Hashes a value the same way identifiers are hashed in the audit log",
+	Long: `Hashes the given value (i.e. a Vault token) with the audit key, producing the same HMAC recorded in the audit log
+for it. The result can be searched for in the audit log to find every call made with the value.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewAuditHash(args[0])
+	},
+}
+
+func init() {
+	auditCmd.AddCommand(auditHashCmd)
+}
+
+func NewAuditHash(value string) {
+	path := auditKeyPath()
+	if path == "" {
+		exitWithError("Error hashing value", fmt.Errorf("neither audit-log nor audit-key is configured"))
+	}
+
+	key, err := audit.LoadKey(path)
+	if err != nil {
+		exitWithError("Error loading audit key", err)
+	}
+
+	printResult(auditHashResult{Hash: audit.Hash(key, value)})
+}
+
+// auditHashResult is the result of the audit hash command.
+type auditHashResult struct {
+	Hash string `json:"hash"`
+}
+
+func (r auditHashResult) Rows() ([]string, [][]string) {
+	return []string{}, [][]string{{r.Hash}}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/files"
+
+	"github.com/spf13/cobra"
+)
+
+var caOut string
+
+// caCmd represents the ca command
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Args:  cobra.NoArgs,
+	Short: "Fetches the CA certificate of the Vault PKI secrets engine",
+	Long:  `Fetches the PEM encoded CA certificate of the PKI secrets engine and prints it or writes it to a file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewPKICA(caOut)
+	},
+}
+
+func init() {
+	pkiCmd.AddCommand(caCmd)
+
+	caCmd.Flags().StringVar(&caOut, "out", "", "File to write the CA certificate to (defaults to stdout)")
+}
+
+func NewPKICA(out string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	ca, err := vaultClient.PKICACertificate(pkiMount)
+	if err != nil {
+		exitWithError("Error fetching CA certificate", err)
+	}
+
+	if out == "" {
+		fmt.Print(string(ca))
+		return
+	}
+
+	if _, err := files.WriteAtomic(out, ca, 0644); err != nil {
+		exitWithError("Error writing CA certificate", err)
+	}
+}
@@ -17,15 +17,23 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/jmgilman/gcli/hook"
 	"github.com/spf13/cobra"
+	"time"
 )
 
+var hookCommand string
+var hookSignal string
+var hookPIDFile string
+var hookReload string
+var hookTimeout time.Duration
+
 // certCmd represents the cert command
 var certCmd = &cobra.Command{
 	Use:   "cert",
-	Args: cobra.MinimumNArgs(1),
+	Args:  cobra.MinimumNArgs(1),
 	Short: "Commands for requesting and fetching SSL certificates",
-	Long: ``,
+	Long:  ``,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("cert called")
 	},
@@ -43,4 +51,34 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// certCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-}
\ No newline at end of file
+}
+
+// addHookFlags adds the flags giving the deploy hooks run when certificates change to the given command.
+func addHookFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&hookCommand, "hook-command", "", "Command run with the system shell when a certificate changes")
+	cmd.Flags().StringVar(&hookSignal, "hook-signal", "", "Signal (i.e. HUP) sent to the process in --hook-pid-file when a certificate changes")
+	cmd.Flags().StringVar(&hookPIDFile, "hook-pid-file", "", "PID file of the process --hook-signal is sent to")
+	cmd.Flags().StringVar(&hookReload, "hook-reload", "", "systemd unit reloaded when a certificate changes")
+	cmd.Flags().DurationVar(&hookTimeout, "hook-timeout", hook.DefaultTimeout, "Maximum time each deploy hook may run")
+}
+
+// deployHooks returns the deploy hooks given by the flags added by addHookFlags.
+func deployHooks() ([]hook.Hook, error) {
+	var hooks []hook.Hook
+	if hookCommand != "" {
+		hooks = append(hooks, hook.Hook{Command: hookCommand, Timeout: hookTimeout})
+	}
+	if hookSignal != "" || hookPIDFile != "" {
+		hooks = append(hooks, hook.Hook{Signal: hookSignal, PIDFile: hookPIDFile, Timeout: hookTimeout})
+	}
+	if hookReload != "" {
+		hooks = append(hooks, hook.Hook{Reload: hookReload, Timeout: hookTimeout})
+	}
+
+	for _, h := range hooks {
+		if err := h.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return hooks, nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/output"
+	"github.com/jmgilman/gcli/vault/client"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var checkServerName string
+var checkDomain string
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check [host:port] ...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Checks the certificates served by TLS endpoints against the certificates stored in Vault",
+	Long: `Connects to each of the given TLS endpoints (the port defaults to 443) and reports the certificate chain it serves,
+when it expires, and whether it's trusted for the server name. The served certificate is compared with the one written
+to Vault by the gcert service for the host (or the wildcard certificate of its parent domain), catching services which
+weren't reloaded after their certificate was renewed:
+
+  gcli cert check example.com mail.example.com:993 10.0.0.5:8443 --server-name example.com
+
+gcli exits with code 10 if an endpoint serves an expired certificate, 12 if one serves a different certificate than
+the one in Vault, or 13 if one serves a chain which isn't trusted for its server name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := newContext(cmd)
+		defer cancel()
+
+		NewCertificateCheck(ctx, args, checkServerName, checkDomain)
+	},
+}
+
+func init() {
+	certCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVar(&checkServerName, "server-name", "", "Server name to send with SNI and verify the certificates for (defaults to the host)")
+	checkCmd.Flags().StringVar(&checkDomain, "domain", "", "Domain of the certificate in Vault to compare with (defaults to the server name)")
+}
+
+func NewCertificateCheck(ctx context.Context, addresses []string, serverName string, domain string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	now := time.Now()
+	logger := logging.L().Named("check")
+	results := make(certificateCheckResult, 0, len(addresses))
+	var checkErr error
+	for _, address := range addresses {
+		entry := certificateCheckEntry{Address: cert.TLSAddress(address), Chain: []chainCertificate{}}
+
+		err := entry.check(ctx, vaultClient, serverName, domain, now)
+		if err != nil {
+			logger.Warn("unable to check endpoint", "address", entry.Address, "error", err)
+			if entry.Status == "" {
+				entry.Status = checkError
+			}
+			entry.Error = err.Error()
+			if checkErr == nil {
+				checkErr = err
+			}
+		}
+		results = append(results, entry)
+	}
+
+	printResult(results)
+
+	var expired, stale, untrusted []string
+	for _, entry := range results {
+		switch entry.Status {
+		case cert.EndpointExpired:
+			expired = append(expired, entry.Address)
+		case cert.EndpointStale:
+			stale = append(stale, entry.Address)
+		case cert.EndpointUntrusted:
+			untrusted = append(untrusted, entry.Address)
+		}
+	}
+
+	switch {
+	case len(expired) > 0:
+		exitWithError("Endpoints need attention", failure.New(failure.Expired,
+			fmt.Errorf("%s served an expired certificate", strings.Join(expired, ", ")),
+			"renew the certificate with gcli cert request and reload the service"))
+	case len(stale) > 0:
+		exitWithError("Endpoints need attention", failure.New(failure.Stale,
+			fmt.Errorf("%s served a different certificate than the one in Vault", strings.Join(stale, ", ")),
+			"deploy the certificate with gcli cert write and reload the service, i.e. with a deploy hook"))
+	case len(untrusted) > 0:
+		exitWithError("Endpoints need attention", failure.New(failure.Untrusted,
+			fmt.Errorf("%s served an untrusted certificate", strings.Join(untrusted, ", ")),
+			"check the service serves the full chain (fullchain.pem) and the certificate covers the server name"))
+	case checkErr != nil:
+		exitWithError("Error checking endpoints", checkErr)
+	}
+}
+
+// vaultCertificate reads the certificate in Vault for the given domain, falling back to the wildcard certificate of its
+// parent domain if there's no certificate for the domain itself. It returns the domain the certificate was found at.
+func vaultCertificate(vaultClient *client.VaultClient, domain string) (string, *cert.Certificate, error) {
+	candidates := []string{domain}
+	if i := strings.Index(domain, "."); i > 0 && !strings.HasPrefix(domain, "*.") && net.ParseIP(domain) == nil {
+		candidates = append(candidates, "*"+domain[i:])
+	}
+
+	var firstErr error
+	for _, candidate := range candidates {
+		certificate, err := vaultClient.GetCertificate(candidate)
+		if err == nil {
+			return candidate, certificate, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		var e *failure.Error
+		if !errors.As(err, &e) || e.Kind != failure.NotFound {
+			break
+		}
+	}
+	return "", &cert.Certificate{}, firstErr
+}
+
+// checkError is the status of an endpoint which couldn't be checked, in addition to the statuses of cert.Endpoint.
+const checkError = "error"
+
+// chainCertificate describes a single certificate in the chain served by an endpoint.
+type chainCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// certificateCheckEntry describes a single endpoint checked by the check command.
+type certificateCheckEntry struct {
+	Address           string             `json:"address"`
+	ServerName        string             `json:"server_name"`
+	Chain             []chainCertificate `json:"chain"`
+	SerialNumber      string             `json:"serial_number"`
+	NotAfter          time.Time          `json:"not_after"`
+	DaysRemaining     int                `json:"days_remaining"`
+	VerifyError       string             `json:"verify_error,omitempty"`
+	Domain            string             `json:"domain,omitempty"`
+	VaultSerialNumber string             `json:"vault_serial_number,omitempty"`
+	Status            string             `json:"status"`
+	Error             string             `json:"error,omitempty"`
+}
+
+// check fills in the entry by inspecting the endpoint and comparing the certificate it serves with the one in Vault.
+func (e *certificateCheckEntry) check(ctx context.Context, vaultClient *client.VaultClient, serverName string,
+	domain string, now time.Time) error {
+	endpoint, err := cert.Inspect(ctx, e.Address, serverName, nil)
+	if err != nil {
+		return err
+	}
+
+	e.ServerName = endpoint.ServerName
+	for _, c := range endpoint.Chain {
+		e.Chain = append(e.Chain, chainCertificate{
+			Subject:      c.Subject.CommonName,
+			Issuer:       c.Issuer.CommonName,
+			SerialNumber: cert.FormatSerial(c.SerialNumber),
+			NotAfter:     c.NotAfter,
+		})
+	}
+
+	leaf := endpoint.Leaf()
+	e.SerialNumber = cert.FormatSerial(leaf.SerialNumber)
+	e.NotAfter = leaf.NotAfter
+	e.DaysRemaining = cert.DaysRemaining(leaf, now)
+	if endpoint.VerifyError != nil {
+		e.VerifyError = endpoint.VerifyError.Error()
+	}
+
+	if domain == "" {
+		domain = endpoint.ServerName
+	}
+	var vaultErr error
+	var certificate *cert.Certificate
+	var stored *x509.Certificate
+	e.Domain, certificate, vaultErr = vaultCertificate(vaultClient, domain)
+	if vaultErr == nil {
+		stored, vaultErr = certificate.X509()
+		if vaultErr == nil {
+			e.VaultSerialNumber = cert.FormatSerial(stored.SerialNumber)
+		}
+	}
+	if vaultErr != nil {
+		stored = nil
+	}
+	e.Status = endpoint.Status(stored, now)
+
+	// The served certificate is still reported when it can't be compared with the one in Vault
+	if vaultErr != nil {
+		return fmt.Errorf("unable to read the certificate for %s from Vault: %w", domain, vaultErr)
+	}
+	return nil
+}
+
+// certificateCheckResult is the result of the check command.
+type certificateCheckResult []certificateCheckEntry
+
+func (r certificateCheckResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r))
+	for i, e := range r {
+		if e.SerialNumber == "" {
+			rows[i] = []string{e.Address, "", "", "", "", "", "error: " + e.Error}
+			continue
+		}
+
+		chain := make([]string, len(e.Chain))
+		for j, c := range e.Chain {
+			chain[j] = c.Subject
+		}
+		status := e.Status
+		switch {
+		case e.Status == cert.EndpointStale:
+			status += " (vault: " + e.VaultSerialNumber + ")"
+		case e.Status == cert.EndpointUntrusted:
+			status += ": " + e.VerifyError
+		case e.Error != "":
+			status += " (" + e.Error + ")"
+		}
+		rows[i] = []string{e.Address, output.Value(chain), e.SerialNumber, e.NotAfter.Format(time.RFC3339),
+			strconv.Itoa(e.DaysRemaining), e.Domain, status}
+	}
+	return []string{"ADDRESS", "CHAIN", "SERIAL", "NOT AFTER", "DAYS LEFT", "VAULT DOMAIN", "STATUS"}, rows
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/envelope"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var decryptEnvelope bool
+
+// decryptCmd represents the decrypt command
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt [key]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Decrypts data with a transit key",
+	Long: `Decrypts the ciphertext input (i.e. vault:v1:...) with the given transit key and outputs the plaintext. With
+--envelope the input is treated as a stream created by "gcli transit encrypt --envelope".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewTransitDecrypt(args[0], decryptEnvelope)
+	},
+}
+
+func init() {
+	transitCmd.AddCommand(decryptCmd)
+
+	decryptCmd.Flags().BoolVar(&decryptEnvelope, "envelope", false, "Stream the input using envelope decryption")
+}
+
+func NewTransitDecrypt(key string, useEnvelope bool) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	if useEnvelope {
+		in, err := openInput(transitIn)
+		if err != nil {
+			exitWithError("Error opening input", err)
+		}
+		defer in.Close()
+
+		out, err := openOutput(transitOut)
+		if err != nil {
+			exitWithError("Error opening output", err)
+		}
+
+		unwrap := func(wrappedKey string) ([]byte, error) {
+			return vaultClient.TransitDecrypt(transitMount, key, wrappedKey)
+		}
+		if err := envelope.Decrypt(out, in, unwrap); err != nil {
+			out.Abort()
+			exitWithError("Error decrypting input", err)
+		}
+		if err := out.Close(); err != nil {
+			exitWithError("Error writing output", err)
+		}
+		return
+	}
+
+	ciphertext, err := readInput(false)
+	if err != nil {
+		exitWithError("Error reading input", err)
+	}
+
+	plaintext, err := vaultClient.TransitDecrypt(transitMount, key, strings.TrimSpace(string(ciphertext)))
+	if err != nil {
+		exitWithError("Error decrypting input", err)
+	}
+
+	if err := writeOutput(plaintext, true); err != nil {
+		exitWithError("Error writing output", err)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/envelope"
+
+	"github.com/spf13/cobra"
+)
+
+var encryptEnvelope bool
+
+// encryptCmd represents the encrypt command
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt [key]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Encrypts data with a transit key",
+	Long: `Encrypts the input with the given transit key and outputs the resulting ciphertext (i.e. vault:v1:...). With
+--envelope the input is streamed and encrypted locally using a transit-wrapped data key, producing a binary file
+which can be decrypted with "gcli transit decrypt --envelope".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewTransitEncrypt(args[0], encryptEnvelope)
+	},
+}
+
+func init() {
+	transitCmd.AddCommand(encryptCmd)
+
+	encryptCmd.Flags().BoolVar(&encryptEnvelope, "envelope", false, "Stream the input using envelope encryption")
+}
+
+func NewTransitEncrypt(key string, useEnvelope bool) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	if useEnvelope {
+		dataKey, wrappedKey, err := vaultClient.TransitDataKey(transitMount, key)
+		if err != nil {
+			exitWithError("Error generating data key", err)
+		}
+
+		in, err := openInput(transitIn)
+		if err != nil {
+			exitWithError("Error opening input", err)
+		}
+		defer in.Close()
+
+		out, err := openOutput(transitOut)
+		if err != nil {
+			exitWithError("Error opening output", err)
+		}
+
+		if err := envelope.Encrypt(out, in, dataKey, wrappedKey); err != nil {
+			out.Abort()
+			exitWithError("Error encrypting input", err)
+		}
+		if err := out.Close(); err != nil {
+			exitWithError("Error writing output", err)
+		}
+		return
+	}
+
+	plaintext, err := readInput(true)
+	if err != nil {
+		exitWithError("Error reading input", err)
+	}
+
+	ciphertext, err := vaultClient.TransitEncrypt(transitMount, key, plaintext)
+	if err != nil {
+		exitWithError("Error encrypting input", err)
+	}
+
+	if err := writeOutput([]byte(ciphertext+"\n"), false); err != nil {
+		exitWithError("Error writing output", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/cert"
+
+	"github.com/spf13/cobra"
+)
+
+var issueAltNames []string
+var issueTTL string
+var issueDir string
+
+// issueCmd represents the issue command
+var issueCmd = &cobra.Command{
+	Use:   "issue [role] [common name]",
+	Args:  cobra.ExactArgs(2),
+	Short: "Issues a new certificate from the Vault PKI secrets engine",
+	Long: `Issues a new certificate and private key for the given common name using the given PKI role. The certificate,
+private key, and CA chain are written to the output directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewPKIIssue(args[0], args[1], issueAltNames, issueTTL, issueDir)
+	},
+}
+
+func init() {
+	pkiCmd.AddCommand(issueCmd)
+
+	issueCmd.Flags().StringSliceVar(&issueAltNames, "alt-names", []string{}, "Additional subject alternative names")
+	issueCmd.Flags().StringVar(&issueTTL, "ttl", "", "Requested certificate TTL (defaults to the role TTL)")
+	issueCmd.Flags().StringVar(&issueDir, "dir", ".", "Directory to write the certificate files to")
+}
+
+func NewPKIIssue(role string, commonName string, altNames []string, ttl string, dir string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	certificate, err := vaultClient.IssueCertificate(pkiMount, role, commonName, altNames, ttl)
+	if err != nil {
+		exitWithError("Error issuing certificate", err)
+	}
+
+	paths, err := cert.Write(dir, certificate)
+	if err != nil {
+		exitWithError("Error writing certificate", err)
+	}
+
+	printResult(pkiCertificateResult{
+		SerialNumber: certificate.SerialNumber,
+		Files:        paths,
+	})
+}
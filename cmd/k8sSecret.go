@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/files"
+
+	"github.com/spf13/cobra"
+)
+
+var k8sSecretNamespace string
+var k8sSecretName string
+var k8sSecretOut string
+
+// k8sSecretCmd represents the k8s-secret command
+var k8sSecretCmd = &cobra.Command{
+	Use:   "k8s-secret [domain]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Generates a Kubernetes TLS secret from the certificate stored in Vault for the given domain",
+	Long: `Reads the certificate written to Vault by the gcert service for the given domain and generates a Kubernetes
+Secret of the kubernetes.io/tls type holding its full chain (tls.crt) and private key (tls.key). The Secret is printed
+or written to a file, so no kubeconfig is needed:
+
+  gcli cert k8s-secret example.com --namespace web | kubectl apply -f -
+  gcli cert k8s-secret example.com --out /var/lib/rancher/k3s/server/manifests/example-com-tls.yaml
+
+The Secret is named after the domain unless --name is given, with a leading wildcard replaced by "wildcard" (i.e.
+wildcard.example.com). It's annotated with the domain, serial number, and expiry of the certificate. Files are written
+with 0600 permissions and only replaced when the certificate changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewCertificateK8sSecret(args[0], k8sSecretName, k8sSecretNamespace, k8sSecretOut)
+	},
+}
+
+func init() {
+	certCmd.AddCommand(k8sSecretCmd)
+
+	k8sSecretCmd.Flags().StringVar(&k8sSecretNamespace, "namespace", "default", "Namespace of the secret")
+	k8sSecretCmd.Flags().StringVar(&k8sSecretName, "name", "", "Name of the secret (defaults to the domain)")
+	k8sSecretCmd.Flags().StringVar(&k8sSecretOut, "out", "", "File to write the secret to (defaults to stdout)")
+}
+
+func NewCertificateK8sSecret(domain string, name string, namespace string, out string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	certificate, err := vaultClient.GetCertificate(domain)
+	if err != nil {
+		exitWithError("Error reading certificate for "+domain, err)
+	}
+
+	if name == "" {
+		name = cert.KubernetesSecretName(domain)
+	}
+	secret, err := cert.KubernetesSecret(certificate, name, namespace)
+	if err != nil {
+		exitWithError("Error generating Kubernetes secret for "+domain, err)
+	}
+
+	if out == "" {
+		fmt.Print(string(secret))
+		return
+	}
+
+	if _, err := files.WriteAtomic(out, secret, 0600); err != nil {
+		exitWithError("Error writing Kubernetes secret", err)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/output"
+
+	"github.com/spf13/cobra"
+)
+
+// kvGetCmd represents the get command of the kv command
+var kvGetCmd = &cobra.Command{
+	Use:   "get [path]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Reads a secret from Vault",
+	Long: `Reads the secret at the given path (i.e. secret/ssl/example.com) and shows its data. Secrets in a KV version 2
+engine are read from their data path (i.e. secret/data/example).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewKVGet(args[0])
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvGetCmd)
+}
+
+func NewKVGet(path string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	data, err := vaultClient.ReadSecret(path)
+	if err != nil {
+		exitWithError("Error reading secret", err)
+	}
+
+	printResult(output.KeyValues(data))
+}
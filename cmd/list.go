@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/output"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var listExpiringWithin string
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "Lists the certificates stored in Vault by the gcert service along with their expiry",
+	Long: `Walks the certificates written to Vault by the gcert service and shows the domain, SANs, issuing Let's Encrypt
+environment (staging or production), expiry, and days remaining of each, soonest to expire first.
+
+With --expiring-within, only certificates expiring within the given window (i.e. 30d or 72h) are shown, and gcli exits
+with code 9 if any are found, or 10 if any have already expired, so it can be used for alerting.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var window time.Duration
+		if listExpiringWithin != "" {
+			var err error
+			window, err = parseDuration(listExpiringWithin)
+			if err != nil {
+				exitWithError("Invalid --expiring-within", err)
+			}
+		}
+
+		NewCertificateList(window)
+	},
+}
+
+func init() {
+	certCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVar(&listExpiringWithin, "expiring-within", "", "Only show certificates expiring within the given window (i.e. 30d) and exit non-zero if there are any")
+}
+
+func NewCertificateList(window time.Duration) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	domains, err := vaultClient.ListCertificates()
+	if err != nil {
+		exitWithError("Error listing certificates", err)
+	}
+
+	now := time.Now()
+	logger := logging.L().Named("list")
+	var results certificateListResult
+	var readErr error
+	for _, domain := range domains {
+		entry := certificateListEntry{Domain: domain, SANs: []string{}}
+
+		certificate, err := vaultClient.GetCertificate(domain)
+		if err == nil {
+			err = entry.parse(certificate, now)
+		}
+		if err != nil {
+			logger.Warn("unable to read certificate", "domain", domain, "error", err)
+			entry.Error = err.Error()
+			if readErr == nil {
+				readErr = err
+			}
+			results = append(results, entry)
+			continue
+		}
+
+		if window > 0 && entry.NotAfter.After(now.Add(window)) {
+			continue
+		}
+		results = append(results, entry)
+	}
+
+	// Soonest to expire first, with certificates which couldn't be read last
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Error == "") != (results[j].Error == "") {
+			return results[i].Error == ""
+		}
+		return results[i].NotAfter.Before(results[j].NotAfter)
+	})
+
+	if results == nil {
+		results = certificateListResult{}
+	}
+	printResult(results)
+
+	if window <= 0 {
+		return
+	}
+
+	var expired, expiring int
+	for _, entry := range results {
+		switch {
+		case entry.Error != "":
+		case !entry.NotAfter.After(now):
+			expired++
+		default:
+			expiring++
+		}
+	}
+
+	switch {
+	case expired > 0:
+		exitWithError("Certificates need renewal", failure.New(failure.Expired,
+			fmt.Errorf("%d certificates have expired and %d more expire within %s", expired, expiring, listExpiringWithin),
+			"renew them with gcli cert request"))
+	case expiring > 0:
+		exitWithError("Certificates need renewal", failure.New(failure.Expiring,
+			fmt.Errorf("%d certificates expire within %s", expiring, listExpiringWithin),
+			"renew them with gcli cert request"))
+	case readErr != nil:
+		exitWithError("Error reading certificates", readErr)
+	}
+}
+
+// certificateListEntry describes a single certificate listed by the list command.
+type certificateListEntry struct {
+	Domain        string    `json:"domain"`
+	SANs          []string  `json:"sans"`
+	Issuer        string    `json:"issuer"`
+	Environment   string    `json:"environment"`
+	SerialNumber  string    `json:"serial_number"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysRemaining int       `json:"days_remaining"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// parse fills in the entry from the given certificate.
+func (e *certificateListEntry) parse(c *cert.Certificate, now time.Time) error {
+	parsed, err := c.X509()
+	if err != nil {
+		return fmt.Errorf("unable to parse certificate: %w", err)
+	}
+
+	e.SANs = parsed.DNSNames
+	e.Issuer = parsed.Issuer.CommonName
+	e.Environment = cert.Environment(parsed)
+	e.SerialNumber = c.SerialNumber
+	e.NotAfter = parsed.NotAfter
+	e.DaysRemaining = cert.DaysRemaining(parsed, now)
+	return nil
+}
+
+// certificateListResult is the result of the list command.
+type certificateListResult []certificateListEntry
+
+func (r certificateListResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r))
+	for i, e := range r {
+		if e.Error != "" {
+			rows[i] = []string{e.Domain, "", "", "", "", "error: " + e.Error}
+			continue
+		}
+		status := "valid"
+		if e.DaysRemaining < 0 {
+			status = "expired"
+		}
+		rows[i] = []string{e.Domain, output.Value(e.SANs), e.Environment, e.NotAfter.Format(time.RFC3339),
+			strconv.Itoa(e.DaysRemaining), status}
+	}
+	return []string{"DOMAIN", "SANS", "ENVIRONMENT", "NOT AFTER", "DAYS LEFT", "STATUS"}, rows
+}
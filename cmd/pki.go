@@ -0,0 +1,48 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/output"
+
+	"github.com/spf13/cobra"
+)
+
+var pkiMount string
+
+// pkiCmd represents the pki command
+var pkiCmd = &cobra.Command{
+	Use:   "pki",
+	Short: "Commands for issuing and managing certificates from the Vault PKI secrets engine",
+	Long: `Provides commands for issuing, revoking, and fetching certificates from the Vault PKI secrets engine. This is
+intended for internal hosts (i.e. .lab) which can't use the public certificates requested through the gcert service.`,
+}
+
+func init() {
+	rootCmd.AddCommand(pkiCmd)
+
+	pkiCmd.PersistentFlags().StringVar(&pkiMount, "mount", "pki", "Mount point of the PKI secrets engine")
+}
+
+// pkiCertificateResult is the result of the commands which write a certificate issued by the PKI secrets engine.
+type pkiCertificateResult struct {
+	SerialNumber string   `json:"serial_number"`
+	Files        []string `json:"files"`
+}
+
+func (r pkiCertificateResult) Rows() ([]string, [][]string) {
+	return []string{"SERIAL NUMBER", "FILES"}, [][]string{{r.SerialNumber, output.Value(r.Files)}}
+}
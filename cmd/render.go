@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/output"
+	"github.com/jmgilman/gcli/render"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var renderTemplate string
+var renderOut string
+var renderMode string
+var renderOnChange string
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Args:  cobra.NoArgs,
+	Short: "Renders a template containing secrets and certificates from Vault to a file",
+	Long: `Renders the given Go template and atomically writes the result to the given output file. Templates can use the
+following functions to pull data from Vault:
+
+  secret "path" "field"  The given field of the secret at the given path
+  cert "domain"          The certificate written by gcert for the domain (.Certificate, .PrivateKey, .Issuer, .Chain)
+  sshCA "mount"          The public key of the SSH CA at the given mount
+
+If the contents of the output file changed, the command given with --on-change is run using the system shell.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, err := strconv.ParseUint(renderMode, 8, 32)
+		if err != nil {
+			exitWithError("Invalid file mode "+renderMode, err)
+		}
+
+		NewRender(&render.Template{
+			Source:      renderTemplate,
+			Destination: renderOut,
+			Mode:        os.FileMode(mode),
+			OnChange:    renderOnChange,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVar(&renderTemplate, "template", "", "Path to the template file to render")
+	renderCmd.Flags().StringVar(&renderOut, "out", "", "Path to write the rendered template to")
+	renderCmd.Flags().StringVar(&renderMode, "mode", "0644", "File mode of the rendered file")
+	renderCmd.Flags().StringVar(&renderOnChange, "on-change", "", "Command to run when the rendered file changes")
+
+	for _, flag := range []string{"template", "out"} {
+		if err := renderCmd.MarkFlagRequired(flag); err != nil {
+			exitWithError("Error marking flag as required", err)
+		}
+	}
+}
+
+func NewRender(t *render.Template) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	changed, err := t.Execute(vaultClient)
+	if err != nil {
+		exitWithError("Error rendering template", err)
+	}
+
+	printResult(renderResult{
+		Destination: t.Destination,
+		Changed:     changed,
+	})
+}
+
+// renderResult is the result of the render command.
+type renderResult struct {
+	Destination string `json:"destination"`
+	Changed     bool   `json:"changed"`
+}
+
+func (r renderResult) Rows() ([]string, [][]string) {
+	return []string{"DESTINATION", "CHANGED"}, [][]string{{r.Destination, output.Value(r.Changed)}}
+}
@@ -19,58 +19,398 @@ import (
 	"context"
 	"fmt"
 	gcert "github.com/jmgilman/gcert/proto"
-	"github.com/jmgilman/gcli/rpc"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/hook"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/manifest"
+	"github.com/jmgilman/gcli/output"
+	"github.com/jmgilman/gcli/retry"
+	"github.com/jmgilman/gcli/vault/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+var requestFile string
+var requestWorkers int
+
 // requestCmd represents the request command
 var requestCmd = &cobra.Command{
-	Use:   "request [gcert server] [domain1] [domain 2] ...",
-	Args: cobra.MinimumNArgs(2),
+	Use: "request [domain1] [domain 2] ...",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if requestFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		if server, ok := legacyServer(args); ok && len(args) == 1 {
+			return fmt.Errorf("no domains were given after the gcert server %s (use --gcert-servers to give the server)", server)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	Short: "Requests the gcert service to renew the given domain's certificate in Vault",
 	Long: `Sends a request to the gcert service, asking it to renew the SSL certificates in Vault for the given domains.
 It will return the paths to where the certificates were written to. You can use the fetch command to get the contents
-of a certificate or the write command to write all certificates to the local filesystem.`,
+of a certificate or the write command to write all certificates to the local filesystem. The gcert servers are given
+by the gcert-servers setting or discovered with the DNS SRV records of the gcert-domain setting.
+
+The domains are validated before being sent to gcert: internationalized domains are converted to punycode, wildcards
+must be the entire leftmost label (i.e. *.example.com), duplicates are removed, and each domain must be in one of the
+zones given by the gcert-zones setting, if any. The problem with each invalid domain is reported.
+
+Deploy hooks given with the --hook flags are run for each certificate whose serial number changed. The certificate is
+passed to hooks in the GCLI_DOMAIN, GCLI_DOMAINS, and GCLI_SERIAL environment variables. If a hook fails or times out,
+gcli exits with code 11.
+
+The gcert server was previously given as the first argument (host:port). It's still accepted in place of
+--gcert-servers, but is deprecated and will be removed.
+
+With --file, the certificates listed in the given manifest are requested concurrently instead:
+
+  workers: 4
+  certificates:
+    - name: web
+      domains: [example.com, www.example.com]
+      endpoint: production            # or staging (the default)
+      output: /etc/ssl/web            # optional, certificates are written to [output]/[domain]
+      post-hook: systemctl reload nginx  # optional, run when the written files change
+      hooks:                          # optional, run in order when the written files change
+        - signal: HUP
+          pid-file: /run/haproxy.pid
+          timeout: 10s                # optional, defaults to 30s
+        - reload: envoy               # systemctl reload envoy
+
+A summary of every certificate is shown once all requests have finished.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		NewCertificateRequest(args[0], args[1:])
+		ctx, cancel := newContext(cmd)
+		defer cancel()
+
+		if requestFile != "" {
+			NewCertificateRequestFile(ctx, requestFile, requestWorkers)
+			return
+		}
+
+		if server, ok := legacyServer(args); ok {
+			if cmd.Flags().Changed("gcert-servers") {
+				exitWithError("Invalid arguments", failure.New(failure.Unknown,
+					fmt.Errorf("the gcert server %s was given both as an argument and with --gcert-servers", server),
+					"remove the server argument, it's replaced by --gcert-servers"))
+			}
+			logging.L().Named("request").Warn("giving the gcert server as an argument is deprecated, use --gcert-servers instead",
+				"server", server)
+			viper.Set("gcert-servers", []string{server})
+			args = args[1:]
+		}
+
+		hooks, err := deployHooks()
+		if err != nil {
+			exitWithError("Invalid deploy hook", err)
+		}
+		NewCertificateRequest(ctx, args, hooks)
 	},
 }
 
 func init() {
 	certCmd.AddCommand(requestCmd)
 
-	// Here you will define your flags and configuration settings.
+	requestCmd.Flags().StringVarP(&requestFile, "file", "f", "", "Manifest listing the certificates to request")
+	requestCmd.Flags().IntVar(&requestWorkers, "workers", 0, "Number of certificates requested at once from a manifest (defaults to the manifest's workers or 4)")
+	addHookFlags(requestCmd)
+}
+
+// legacyServer returns the gcert server given as the first of the given arguments (host:port), which is how it was given
+// before the gcert-servers setting. Domains can't include a port, so it can't be mistaken for one.
+func legacyServer(args []string) (string, bool) {
+	if len(args) == 0 {
+		return "", false
+	}
+	_, port, err := net.SplitHostPort(args[0])
+	if err != nil {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", false
+	}
+	return args[0], true
+}
+
+func NewCertificateRequest(ctx context.Context, domains []string, hooks []hook.Hook) {
+	valid, problems := validateDomains(domains)
+	if len(problems) > 0 {
+		result := make(domainProblemsResult, len(problems))
+		for i, p := range problems {
+			result[i] = domainProblem{Domain: p.Domain, Problem: p.Reason}
+		}
+		printResult(result)
+		exitWithError("Invalid domains", failure.New(failure.Unknown,
+			fmt.Errorf("%d of %d domains can't be requested", len(problems), len(domains)), domainsHint))
+	}
+	domains = valid
+
+	// The serial numbers of the current certificates are needed to only run the hooks for certificates which change
+	var vaultClient *client.VaultClient
+	serials := map[string]string{}
+	if len(hooks) > 0 {
+		var err error
+		vaultClient, err = newVaultClient()
+		if err != nil {
+			exitWithError("Unable to configure Vault client", err)
+		}
+		for _, domain := range domains {
+			if c, err := vaultClient.GetCertificate(domain); err == nil {
+				serials[domain] = c.SerialNumber
+			}
+		}
+	}
+
+	conn, err := dialGcert(ctx)
+	if err != nil {
+		exitWithError("Error connecting to gcert server", err)
+	}
+	defer conn.Close()
+
+	paths, err := requestCertificate(ctx, gcert.NewCertificateServiceClient(conn), domains,
+		gcert.CertificateRequest_LE_STAGING)
+	if err != nil {
+		exitWithError("Error requesting certificate", err)
+	}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// requestCmd.PersistentFlags().String("foo", "", "A help for foo")
+	var hookErr error
+	if len(hooks) > 0 {
+		for _, domain := range vaultPathDomains(paths) {
+			c, err := vaultClient.GetCertificate(domain)
+			if err != nil {
+				exitWithError("Error reading certificate for "+domain, err)
+			}
+			if c.SerialNumber != "" && c.SerialNumber == serials[domain] {
+				logging.L().Named("request").Info("certificate didn't change, skipping deploy hooks", "domain", domain)
+				continue
+			}
+
+			err = hook.RunAll(ctx, hooks, hook.Context{Domain: domain, Domains: domains, SerialNumber: c.SerialNumber})
+			if err != nil && hookErr == nil {
+				hookErr = err
+			}
+		}
+	}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// requestCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	printResult(certificateRequestResult{
+		Domains:    domains,
+		VaultPaths: paths,
+	})
+	if hookErr != nil {
+		exitWithError("Error running deploy hooks", hookErr)
+	}
 }
 
-func NewCertificateRequest(server string, domains []string) {
-	conn, err := rpc.Dial(server, true)
+func NewCertificateRequestFile(ctx context.Context, path string, workers int) {
+	m, err := manifest.Load(path)
+	if err != nil {
+		exitWithError("Error loading manifest", err)
+	}
+	if workers <= 0 {
+		workers = m.Workers
+	}
+
+	conn, err := dialGcert(ctx)
 	if err != nil {
-		fmt.Println("Unable to connec to RPC server at", server)
-		os.Exit(1)
+		exitWithError("Error connecting to gcert server", err)
+	}
+	defer conn.Close()
+	gcertClient := gcert.NewCertificateServiceClient(conn)
+
+	// Vault is only needed to write certificates
+	var vaultClient *client.VaultClient
+	for _, g := range m.Certificates {
+		if g.Output != "" {
+			vaultClient, err = newVaultClient()
+			if err != nil {
+				exitWithError("Unable to configure Vault client", err)
+			}
+			break
+		}
+	}
+
+	results := make(batchRequestResult, len(m.Certificates))
+	errs := manifest.Run(ctx, m.Certificates, workers, func(ctx context.Context, i int, g manifest.Group) error {
+		logger := logging.L().Named("request").With("name", g.Name)
+		logger.Info("requesting certificate", "domains", g.Domains, "endpoint", g.Endpoint)
+
+		result := &results[i]
+
+		domains, problems := validateDomains(g.Domains)
+		if len(problems) > 0 {
+			return domainsError(problems)
+		}
+
+		paths, err := requestCertificate(ctx, gcertClient, domains, gcertEndpoint(g.Endpoint))
+		if err != nil {
+			return err
+		}
+		result.VaultPaths = paths
+
+		result.Files, err = g.Deploy(ctx, vaultClient, vaultPathDomains(paths))
+		return err
+	})
+
+	var failed error
+	for i, err := range errs {
+		results[i].Name = m.Certificates[i].Name
+		results[i].Domains = m.Certificates[i].Domains
+		results[i].Status = "ok"
+		if results[i].VaultPaths == nil {
+			results[i].VaultPaths = []string{}
+		}
+		if results[i].Files == nil {
+			results[i].Files = []string{}
+		}
+		if err != nil {
+			results[i].Status = "failed"
+			if failure.Classify(err).Kind == failure.HookFailed {
+				results[i].Status = "hook failed"
+			}
+			results[i].Error = err.Error()
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+
+	printResult(results)
+	if failed != nil {
+		os.Exit(failure.ExitCode(failed))
 	}
+}
 
-	client := gcert.NewCertificateServiceClient(conn)
+// requestCertificate asks the gcert service to issue a certificate for the given domains from the given endpoint,
+// returning the Vault paths the certificates were written to. Errors are returned as a failure.Error.
+func requestCertificate(ctx context.Context, client gcert.CertificateServiceClient, domains []string,
+	endpoint gcert.CertificateRequest_Endpoint) ([]string, error) {
 	request := &gcert.CertificateRequest{
 		Domains:  domains,
-		Endpoint: gcert.CertificateRequest_LE_STAGING,
+		Endpoint: endpoint,
+	}
+
+	// gcert may have already ordered the certificate once the request reached it, and ordering it again could issue a
+	// duplicate and use up the Let's Encrypt rate limit. The request is only retried if it was never sent, which is the
+	// case when no connection to the server could be picked for it (and so no peer was recorded).
+	var resp *gcert.CertificateResponse
+	var sent bool
+	retryable := func(err error) bool {
+		return !sent && retry.Retryable(err)
+	}
+	err := newRetryPolicy().DoWith(ctx, "gcert request", retryable, func() error {
+		var p peer.Peer
+		var err error
+		resp, err = client.GetCertificate(ctx, request, grpc.Peer(&p))
+		sent = p.Addr != nil
+		return err
+	})
+	if err != nil {
+		return []string{}, failure.FromGRPC(err)
+	}
+	if !resp.Success {
+		return []string{}, failure.New(failure.GcertRejected,
+			fmt.Errorf("the gcert service was unable to issue certificates for %s", strings.Join(domains, ", ")),
+			"check the domains are valid and the logs of the gcert service")
+	}
+
+	return resp.VaultPaths, nil
+}
+
+// validateDomains normalizes the given domains before they're sent to the gcert service, removing duplicates and
+// checking they're in the zones given by the gcert-zones setting. The problem with each domain which can't be requested
+// is returned.
+func validateDomains(domains []string) ([]string, []cert.Problem) {
+	valid, duplicates, problems := cert.ValidateDomains(domains, viper.GetStringSlice("gcert-zones"))
+	for _, domain := range duplicates {
+		logging.L().Named("request").Warn("removing duplicate domain", "domain", domain)
+	}
+	return valid, problems
+}
+
+// domainsHint is the hint given when requested domains fail validation.
+const domainsHint = "check the spelling of the domains and that gcert-zones lists their zones"
+
+// domainsError returns an error listing the given problems with the requested domains.
+func domainsError(problems []cert.Problem) error {
+	reasons := make([]string, len(problems))
+	for i, p := range problems {
+		reasons[i] = p.Error()
 	}
+	return failure.New(failure.Unknown, fmt.Errorf("invalid domains: %s", strings.Join(reasons, "; ")), domainsHint)
+}
+
+// gcertEndpoint returns the gcert endpoint for the given manifest endpoint (staging or production).
+func gcertEndpoint(endpoint string) gcert.CertificateRequest_Endpoint {
+	if endpoint == manifest.Production {
+		return gcert.CertificateRequest_LE
+	}
+	return gcert.CertificateRequest_LE_STAGING
+}
+
+// vaultPathDomains returns the domain of each of the given certificate paths returned by the gcert service.
+func vaultPathDomains(paths []string) []string {
+	domains := make([]string, len(paths))
+	for i, path := range paths {
+		domains[i] = strings.TrimPrefix(path, cert.BasePath)
+	}
+	return domains
+}
+
+// certificateRequestResult is the result of the request command.
+type certificateRequestResult struct {
+	Domains    []string `json:"domains"`
+	VaultPaths []string `json:"vault_paths"`
+}
+
+func (r certificateRequestResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r.VaultPaths))
+	for i, path := range r.VaultPaths {
+		rows[i] = []string{path}
+	}
+	return []string{"VAULT PATH"}, rows
+}
+
+// domainProblem describes why a single domain given to the request command can't be requested.
+type domainProblem struct {
+	Domain  string `json:"domain"`
+	Problem string `json:"problem"`
+}
+
+// domainProblemsResult is the result of the request command when domains fail validation.
+type domainProblemsResult []domainProblem
 
-	resp, err := client.GetCertificate(context.Background(), request)
-	if err != nil || !resp.Success {
-		fmt.Println("Error requesting certificate:", err)
-		os.Exit(1)
+func (r domainProblemsResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r))
+	for i, p := range r {
+		rows[i] = []string{p.Domain, p.Problem}
 	}
+	return []string{"DOMAIN", "PROBLEM"}, rows
+}
+
+// certificateGroupResult is the outcome of requesting a single certificate group from a manifest.
+type certificateGroupResult struct {
+	Name       string   `json:"name"`
+	Domains    []string `json:"domains"`
+	Status     string   `json:"status"`
+	VaultPaths []string `json:"vault_paths"`
+	Files      []string `json:"files"`
+	Error      string   `json:"error,omitempty"`
+}
 
-	fmt.Printf("New certificates saved at:\n\n%s\n", strings.Join(resp.VaultPaths, "\n"))
+// batchRequestResult is the result of the request command with a manifest.
+type batchRequestResult []certificateGroupResult
+
+func (r batchRequestResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r))
+	for i, g := range r {
+		rows[i] = []string{g.Name, output.Value(g.Domains), g.Status, output.Value(len(g.Files)), g.Error}
+	}
+	return []string{"NAME", "DOMAINS", "STATUS", "FILES CHANGED", "ERROR"}, rows
 }
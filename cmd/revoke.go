@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/output"
+
+	"github.com/spf13/cobra"
+)
+
+// revokeCmd represents the revoke command
+var revokeCmd = &cobra.Command{
+	Use:   "revoke [serial number]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Revokes a certificate issued by the Vault PKI secrets engine",
+	Long:  `Revokes the certificate with the given serial number (i.e. 39:dd:2e:...) and rotates the engine's CRL.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewPKIRevoke(args[0])
+	},
+}
+
+func init() {
+	pkiCmd.AddCommand(revokeCmd)
+}
+
+func NewPKIRevoke(serial string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	if err := vaultClient.RevokeCertificate(pkiMount, serial); err != nil {
+		exitWithError("Error revoking certificate", err)
+	}
+
+	printResult(revokeResult{
+		SerialNumber: serial,
+		Revoked:      true,
+	})
+}
+
+// revokeResult is the result of the revoke command.
+type revokeResult struct {
+	SerialNumber string `json:"serial_number"`
+	Revoked      bool   `json:"revoked"`
+}
+
+func (r revokeResult) Rows() ([]string, [][]string) {
+	return []string{"SERIAL NUMBER", "REVOKED"}, [][]string{{r.SerialNumber, output.Value(r.Revoked)}}
+}
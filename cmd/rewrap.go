@@ -0,0 +1,89 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/envelope"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var rewrapEnvelope bool
+
+// rewrapCmd represents the rewrap command
+var rewrapCmd = &cobra.Command{
+	Use:   "rewrap [key]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Rewraps ciphertext with the latest version of a transit key",
+	Long: `Re-encrypts the ciphertext input with the latest version of the given transit key without exposing the
+plaintext. With --envelope only the wrapped data key in the header of an envelope stream is rewrapped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewTransitRewrap(args[0], rewrapEnvelope)
+	},
+}
+
+func init() {
+	transitCmd.AddCommand(rewrapCmd)
+
+	rewrapCmd.Flags().BoolVar(&rewrapEnvelope, "envelope", false, "Rewrap the data key of an envelope stream")
+}
+
+func NewTransitRewrap(key string, useEnvelope bool) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	if useEnvelope {
+		in, err := openInput(transitIn)
+		if err != nil {
+			exitWithError("Error opening input", err)
+		}
+		defer in.Close()
+
+		out, err := openOutput(transitOut)
+		if err != nil {
+			exitWithError("Error opening output", err)
+		}
+
+		rewrap := func(wrappedKey string) (string, error) {
+			return vaultClient.TransitRewrap(transitMount, key, wrappedKey)
+		}
+		if err := envelope.Rewrap(out, in, rewrap); err != nil {
+			out.Abort()
+			exitWithError("Error rewrapping input", err)
+		}
+		if err := out.Close(); err != nil {
+			exitWithError("Error writing output", err)
+		}
+		return
+	}
+
+	ciphertext, err := readInput(false)
+	if err != nil {
+		exitWithError("Error reading input", err)
+	}
+
+	rewrapped, err := vaultClient.TransitRewrap(transitMount, key, strings.TrimSpace(string(ciphertext)))
+	if err != nil {
+		exitWithError("Error rewrapping input", err)
+	}
+
+	if err := writeOutput([]byte(rewrapped+"\n"), false); err != nil {
+		exitWithError("Error writing output", err)
+	}
+}
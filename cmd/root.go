@@ -16,35 +16,143 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/jmgilman/gcli/audit"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/output"
+	"github.com/jmgilman/gcli/retry"
+	"github.com/jmgilman/gcli/rpc"
+	"github.com/jmgilman/gcli/vault/client"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
 
+// interruptGracePeriod is how long a command is given to stop after being interrupted before gcli exits regardless.
+const interruptGracePeriod = 2 * time.Second
+
 var cfgFile string
+var profile string
+var timeout time.Duration
+var outputFormat string
+var verbose int
+var logLevel string
+var logFormat string
+var retryMaxAttempts int
+var retryMinBackoff time.Duration
+var retryMaxBackoff time.Duration
+var auditLogFile string
+var auditKeyFile string
+var gcertServers []string
+var gcertDomain string
+var gcertAuth string
+var gcertAuthRole string
+var gcertDialTimeout time.Duration
+var gcertKeepalive time.Duration
+var gcertMaxMessageSize int
+var gcertHealthCheck bool
+var gcertZones []string
+var gcertCACert string
+var gcertTLSServerName string
+var gcertInsecure bool
 var vaultToken string
 var vaultAddress string
+var vaultAddresses []string
+var vaultNamespace string
+var vaultCACert string
+var vaultCAPath string
+var vaultClientCert string
+var vaultClientKey string
+var vaultTLSServerName string
+var vaultTLSSkipVerify bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gcli",
 	Short: "A CLI utility for managing the Gilman lab (glab)",
 	Long: ``,
+	// Errors are printed by Execute along with their hint
+	SilenceErrors: true,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
 }
 
+// handlesInterrupts is the annotation of commands which handle being interrupted themselves (i.e. to finish the work in
+// progress), so Execute doesn't cancel their context or exit when gcli is interrupted.
+const handlesInterrupts = "handles-interrupts"
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The context given to commands is cancelled when gcli is interrupted (i.e. Ctrl-C), unless the command has the
+// handlesInterrupts annotation.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if cmd, _, err := rootCmd.Find(os.Args[1:]); err != nil || cmd.Annotations[handlesInterrupts] == "" {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			logging.L().Warn("interrupted, cancelling")
+			cancel()
+
+			// Not every call can be cancelled, so don't wait on them forever
+			time.Sleep(interruptGracePeriod)
+			fmt.Fprintln(os.Stderr, "Interrupted")
+			os.Exit(130)
+		}()
+	}
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		exitWithError("Error", err)
+	}
+}
+
+// exitWithError prints the given message and error to stderr, along with a hint on resolving the error if there is one,
+// and exits with the exit code for the kind of error. The exit codes are documented in the README.
+func exitWithError(msg string, err error) {
+	e := failure.Classify(err)
+	logging.L().Debug("command failed", "kind", e.Kind.String(), "exit_code", e.Kind.ExitCode(), "error", err)
+	fmt.Fprintf(os.Stderr, "%s: %s\n", msg, e)
+	if e.Hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", e.Hint)
 	}
+	os.Exit(e.Kind.ExitCode())
+}
+
+// printResult writes the given result of a command to stdout in the format given by the output setting.
+func printResult(result interface{}) {
+	if err := output.Write(os.Stdout, viper.GetString("output"), result); err != nil {
+		exitWithError("Error writing output", err)
+	}
+}
+
+// newContext returns a context derived from the context of the given command which times out after the duration
+// given by the timeout setting. A timeout of zero means the context never times out.
+func newContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if timeout := viper.GetDuration("timeout"); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
 }
 
 func init() {
@@ -52,17 +160,334 @@ func init() {
 
 	// Viper flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gcli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to use (defaults to the profile config value)")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Maximum time to wait on the Vault and gcert servers, 0 waits forever (i.e. 30s)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", output.Table, "Output format of command results ("+strings.Join(output.Formats, ", ")+")")
+
+	// Logging flags
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Log more details to stderr, repeat for more (i.e. -vv)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log level ("+strings.Join(logging.Levels, ", ")+"), overrides -v")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.Text, "Log format (text, json)")
+
+	// Retry flags
+	defaultPolicy := retry.DefaultPolicy()
+	rootCmd.PersistentFlags().IntVar(&retryMaxAttempts, "retry-max-attempts", defaultPolicy.MaxAttempts, "Maximum number of attempts made for Vault and gcert calls which fail with a transient error")
+	rootCmd.PersistentFlags().DurationVar(&retryMinBackoff, "retry-min-backoff", defaultPolicy.MinBackoff, "Wait before the first retry, doubling for each retry after it")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxBackoff, "retry-max-backoff", defaultPolicy.MaxBackoff, "Maximum wait between retries")
+
+	// Audit flags
+	rootCmd.PersistentFlags().StringVar(&auditLogFile, "audit-log", "", "File every Vault and gcert call is recorded in as JSON lines, disabled if empty")
+	rootCmd.PersistentFlags().StringVar(&auditKeyFile, "audit-key", "", "File containing the key identifiers in the audit log are hashed with, created if missing (defaults to the audit log path + .key)")
+
+	// gcert flags
+	rootCmd.PersistentFlags().StringSliceVar(&gcertServers, "gcert-servers", []string{}, "Addresses (host:port) of the gcert servers, tried in order")
+	rootCmd.PersistentFlags().StringVar(&gcertDomain, "gcert-domain", "", "Domain the gcert servers are discovered in with DNS SRV (_gcert._tcp.<domain>) when --gcert-servers isn't given")
+	rootCmd.PersistentFlags().StringVar(&gcertAuth, "gcert-auth", rpc.AuthNone, "Credentials sent to the gcert service ("+strings.Join(rpc.AuthMethods, ", ")+")")
+	rootCmd.PersistentFlags().StringVar(&gcertAuthRole, "gcert-auth-role", "gcert", "Vault identity token role used to sign the JWT sent with --gcert-auth jwt")
+	rootCmd.PersistentFlags().DurationVar(&gcertDialTimeout, "gcert-dial-timeout", 10*time.Second, "Maximum time to wait for a connection to the gcert server, 0 connects in the background")
+	rootCmd.PersistentFlags().DurationVar(&gcertKeepalive, "gcert-keepalive", 0, "Interval the gcert connection is pinged at when idle, 0 disables pings")
+	rootCmd.PersistentFlags().IntVar(&gcertMaxMessageSize, "gcert-max-message-size", 0, "Maximum size in bytes of gcert messages, 0 uses the gRPC default (4MB)")
+	rootCmd.PersistentFlags().BoolVar(&gcertHealthCheck, "gcert-health-check", false, "Check the gcert server is serving (grpc.health.v1) before making requests")
+	rootCmd.PersistentFlags().StringSliceVar(&gcertZones, "gcert-zones", []string{}, "DNS zones the gcert service issues certificates in, domains outside them are rejected before requesting (any if empty)")
+	rootCmd.PersistentFlags().StringVar(&gcertCACert, "gcert-ca-cert", "", "CA certificate file used to verify the gcert server (defaults to the system roots)")
+	rootCmd.PersistentFlags().StringVar(&gcertTLSServerName, "gcert-tls-server-name", "", "Name the gcert server certificate is verified for (defaults to the server host)")
+	rootCmd.PersistentFlags().BoolVar(&gcertInsecure, "gcert-insecure", false, "Connect to the gcert server without TLS, which can't be used with --gcert-auth token")
 
 	// Vault flags
 	rootCmd.PersistentFlags().StringVar(&vaultAddress, "vault-address", "", "Vault server address (defaults to VAULT_ADDR)")
-	err := viper.BindPFlag("", rootCmd.PersistentFlags().Lookup("role"))
-
+	rootCmd.PersistentFlags().StringSliceVar(&vaultAddresses, "vault-addresses", []string{}, "Addresses of every node of an HA Vault cluster, overrides --vault-address")
 	rootCmd.PersistentFlags().StringVar(&vaultToken, "vault-token", "", "Vault token (defaults to VAULT_TOKEN)")
-	err = viper.BindPFlag("", rootCmd.PersistentFlags().Lookup("role"))
+	rootCmd.PersistentFlags().StringVar(&vaultNamespace, "vault-namespace", "", "Vault namespace (defaults to VAULT_NAMESPACE)")
+
+	// Vault TLS flags
+	rootCmd.PersistentFlags().StringVar(&vaultCACert, "vault-ca-cert", "", "CA certificate file used to verify the Vault server (defaults to VAULT_CACERT)")
+	rootCmd.PersistentFlags().StringVar(&vaultCAPath, "vault-ca-path", "", "Directory of CA certificates used to verify the Vault server (defaults to VAULT_CAPATH)")
+	rootCmd.PersistentFlags().StringVar(&vaultClientCert, "vault-client-cert", "", "Client certificate file for TLS authentication (defaults to VAULT_CLIENT_CERT)")
+	rootCmd.PersistentFlags().StringVar(&vaultClientKey, "vault-client-key", "", "Client key file for TLS authentication (defaults to VAULT_CLIENT_KEY)")
+	rootCmd.PersistentFlags().StringVar(&vaultTLSServerName, "vault-tls-server-name", "", "Name used as the SNI host when connecting to Vault (defaults to VAULT_TLS_SERVER_NAME)")
+	rootCmd.PersistentFlags().BoolVar(&vaultTLSSkipVerify, "vault-tls-skip-verify", false, "Disable verification of the Vault server certificate (defaults to VAULT_SKIP_VERIFY)")
+
+	// Flags take precedence over environment variables, which take precedence over the config file
+	for _, name := range []string{"profile", "timeout", "output", "log-level", "log-format", "retry-max-attempts",
+		"retry-min-backoff", "retry-max-backoff", "audit-log", "audit-key", "gcert-servers", "gcert-domain", "gcert-auth", "gcert-auth-role",
+		"gcert-dial-timeout", "gcert-keepalive", "gcert-max-message-size", "gcert-health-check", "gcert-zones", "gcert-ca-cert", "gcert-tls-server-name", "gcert-insecure", "vault-address", "vault-addresses", "vault-token", "vault-namespace", "vault-ca-cert",
+		"vault-ca-path", "vault-client-cert", "vault-client-key", "vault-tls-server-name", "vault-tls-skip-verify"} {
+		if err := viper.BindPFlag(name, rootCmd.PersistentFlags().Lookup(name)); err != nil {
+			exitWithError("Error binding to flags", err)
+		}
+	}
+}
 
+// newVaultClient returns a VaultClient configured from the Vault environment variables, with the address, token,
+// namespace, and TLS settings overridden by the global flags, gcli environment variables, or config profile when they
+// are given.
+func newVaultClient() (*client.VaultClient, error) {
+	vaultClient, err := client.NewDefaultClientWithTLS(&api.TLSConfig{
+		CACert:        viper.GetString("vault-ca-cert"),
+		CAPath:        viper.GetString("vault-ca-path"),
+		ClientCert:    viper.GetString("vault-client-cert"),
+		ClientKey:     viper.GetString("vault-client-key"),
+		TLSServerName: viper.GetString("vault-tls-server-name"),
+		Insecure:      viper.GetBool("vault-tls-skip-verify"),
+	})
 	if err != nil {
-		fmt.Println("Error binding to flags:", err)
-		os.Exit(1)
+		return &client.VaultClient{}, err
+	}
+
+	if err := vaultClient.SetConfigValues(viper.GetString("vault-address"), viper.GetString("vault-token")); err != nil {
+		return &client.VaultClient{}, err
+	}
+
+	if namespace := viper.GetString("vault-namespace"); namespace != "" {
+		vaultClient.SetNamespace(namespace)
+	}
+
+	if timeout := viper.GetDuration("timeout"); timeout > 0 {
+		vaultClient.SetTimeout(timeout)
+	}
+
+	vaultClient.SetRetryPolicy(newRetryPolicy())
+
+	// The client is still returned when none of the nodes are available so their health can be reported
+	if addresses := viper.GetStringSlice("vault-addresses"); len(addresses) > 0 {
+		if _, err := vaultClient.SetAddresses(addresses); err != nil {
+			if errors.Is(err, client.ErrNoAvailableNodes) {
+				return vaultClient, err
+			}
+			return &client.VaultClient{}, err
+		}
+	}
+
+	auditLog, err := newAuditLog()
+	if err != nil {
+		return &client.VaultClient{}, err
+	}
+	if auditLog != nil {
+		if err := vaultClient.SetAuditLog(auditLog); err != nil {
+			return &client.VaultClient{}, err
+		}
+	}
+
+	logging.L().Named("config").Debug("configured Vault client", "address", vaultClient.Address(),
+		"namespace", vaultClient.Namespace())
+	return vaultClient, nil
+}
+
+// parseDuration parses a duration which may also be given in days (i.e. 30d) in addition to the units accepted by
+// time.ParseDuration.
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// newRetryPolicy returns the retry policy configured by the retry settings.
+func newRetryPolicy() *retry.Policy {
+	return &retry.Policy{
+		MaxAttempts: viper.GetInt("retry-max-attempts"),
+		MinBackoff:  viper.GetDuration("retry-min-backoff"),
+		MaxBackoff:  viper.GetDuration("retry-max-backoff"),
+	}
+}
+
+// openedAuditLog is the audit log shared by every client, opened by the first call to newAuditLog.
+var openedAuditLog *audit.Log
+
+// newAuditLog returns the audit log given by the audit-log setting, or nil if audit logging is disabled.
+func newAuditLog() (*audit.Log, error) {
+	path := viper.GetString("audit-log")
+	if path == "" || openedAuditLog != nil {
+		return openedAuditLog, nil
+	}
+
+	key, err := audit.LoadKey(auditKeyPath())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load audit key: %w", err)
+	}
+
+	l, err := audit.Open(path, key, viper.GetString("profile"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+	openedAuditLog = l
+
+	logging.L().Named("config").Debug("recording calls in audit log", "path", path)
+	return openedAuditLog, nil
+}
+
+// auditKeyPath returns the path to the audit key given by the audit-key setting, defaulting to the audit log path with
+// a .key extension.
+func auditKeyPath() string {
+	if path := viper.GetString("audit-key"); path != "" {
+		return path
+	}
+	if path := viper.GetString("audit-log"); path != "" {
+		return path + ".key"
+	}
+	return ""
+}
+
+// dialGcert connects to the first reachable gcert server given by the gcert-servers setting, or discovered in the
+// domain given by the gcert-domain setting. Errors are returned as a failure.Error.
+func dialGcert(ctx context.Context) (*grpc.ClientConn, error) {
+	opts, err := gcertDialOptions()
+	if err != nil {
+		return &grpc.ClientConn{}, err
+	}
+
+	servers, err := gcertServerAddresses(ctx)
+	if err != nil {
+		return &grpc.ClientConn{}, err
+	}
+
+	conn, server, err := rpc.DialAny(ctx, servers, opts...)
+	if err != nil {
+		var handshakeErr *rpc.HandshakeError
+		if errors.As(err, &handshakeErr) {
+			return &grpc.ClientConn{}, failure.New(failure.Network, err, "gcert connections use TLS by default, set "+
+				"gcert-insecure if the server doesn't use TLS, otherwise check gcert-ca-cert and gcert-tls-server-name")
+		}
+
+		e := failure.FromGRPC(err)
+		if e.Kind == failure.Network {
+			e.Hint = "check the gcert server addresses and that the servers are running, or increase --gcert-dial-timeout"
+		}
+		return &grpc.ClientConn{}, e
+	}
+
+	logging.L().Named("config").Debug("connected to gcert server", "server", server)
+	return conn, nil
+}
+
+// gcertServerAddresses returns the addresses of the gcert servers given by the gcert-servers setting, falling back to
+// looking them up with DNS SRV in the domain given by the gcert-domain setting.
+func gcertServerAddresses(ctx context.Context) ([]string, error) {
+	if servers := viper.GetStringSlice("gcert-servers"); len(servers) > 0 {
+		return servers, nil
+	}
+
+	domain := viper.GetString("gcert-domain")
+	if domain == "" {
+		return []string{}, failure.New(failure.Unknown, errors.New("no gcert server is configured"),
+			"set gcert-servers in the config file (or --gcert-servers), or gcert-domain to discover them with DNS SRV")
+	}
+
+	servers, err := rpc.LookupServers(ctx, net.DefaultResolver, domain)
+	if err != nil {
+		return []string{}, failure.New(failure.NotFound, err,
+			"check the _gcert._tcp."+domain+" SRV records exist, or set gcert-servers instead")
+	}
+	return servers, nil
+}
+
+// gcertUserAgent is the user agent sent with every call to the gcert service.
+const gcertUserAgent = "gcli"
+
+// gcertKeepaliveTimeout is how long a keepalive ping of the gcert server is waited on before the connection is closed.
+const gcertKeepaliveTimeout = 20 * time.Second
+
+// gcertDialOptions returns the options used to dial the gcert service, configured by the gcert settings. The connection
+// uses TLS unless the gcert-insecure setting is true. Calls are sent with the credentials given by the gcert-auth
+// setting, and are recorded in the audit log if it's enabled.
+func gcertDialOptions() ([]rpc.Option, error) {
+	method := viper.GetString("gcert-auth")
+	opts := []rpc.Option{rpc.WithUserAgent(gcertUserAgent)}
+	if viper.GetBool("gcert-insecure") {
+		// The Vault token could be replayed against Vault by anyone able to read the connection
+		if method == rpc.AuthToken {
+			return []rpc.Option{}, failure.New(failure.Unknown,
+				errors.New("the Vault token can't be sent to the gcert service over an insecure connection"),
+				"remove gcert-insecure to connect with TLS, or use --gcert-auth jwt")
+		}
+		if method != rpc.AuthNone {
+			logging.L().Named("config").Warn("sending credentials to the gcert service over an insecure connection",
+				"auth", method)
+		}
+		opts = append(opts, rpc.WithInsecure())
+	} else {
+		config, err := rpc.TLSConfig(viper.GetString("gcert-ca-cert"), viper.GetString("gcert-tls-server-name"))
+		if err != nil {
+			return []rpc.Option{}, failure.New(failure.Unknown, err, "check the file given by gcert-ca-cert")
+		}
+		opts = append(opts, rpc.WithTLS(config))
+	}
+	if timeout := viper.GetDuration("gcert-dial-timeout"); timeout > 0 {
+		opts = append(opts, rpc.WithTimeout(timeout))
+	}
+	if interval := viper.GetDuration("gcert-keepalive"); interval > 0 {
+		opts = append(opts, rpc.WithKeepalive(interval, gcertKeepaliveTimeout))
+	}
+	if size := viper.GetInt("gcert-max-message-size"); size > 0 {
+		opts = append(opts, rpc.WithMaxMessageSize(size))
+	}
+	if viper.GetBool("gcert-health-check") {
+		opts = append(opts, rpc.WithHealthCheck(""))
+	}
+
+	var interceptors []grpc.UnaryClientInterceptor
+
+	if method != rpc.AuthNone {
+		credential, err := gcertCredential(method)
+		if err != nil {
+			return []rpc.Option{}, err
+		}
+
+		interceptor, err := rpc.AuthInterceptor(method, credential)
+		if err != nil {
+			return []rpc.Option{}, err
+		}
+		interceptors = append(interceptors, interceptor)
+	}
+
+	// The audit interceptor runs after the authentication interceptor so it can record the credential's HMAC
+	auditLog, err := newAuditLog()
+	if err != nil {
+		return []rpc.Option{}, err
+	}
+	if auditLog != nil {
+		interceptors = append(interceptors, audit.UnaryClientInterceptor(auditLog))
+	}
+
+	if len(interceptors) > 0 {
+		opts = append(opts, rpc.WithDialOptions(grpc.WithChainUnaryInterceptor(interceptors...)))
+	}
+	return opts, nil
+}
+
+// gcertCredential returns a function which gets the credential sent to the gcert service with the given method from
+// Vault.
+func gcertCredential(method string) (rpc.Credential, error) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case rpc.AuthToken:
+		return func(ctx context.Context) (string, error) {
+			if vaultClient.Token() == "" {
+				return "", failure.New(failure.AuthFailed, errors.New("no Vault token is configured"),
+					"set a Vault token with --vault-token or VAULT_TOKEN")
+			}
+			return vaultClient.Token(), nil
+		}, nil
+	case rpc.AuthJWT:
+		role := viper.GetString("gcert-auth-role")
+		return func(ctx context.Context) (string, error) {
+			token, err := vaultClient.IdentityToken(ctx, role)
+			if err != nil {
+				return "", failure.FromVault(err)
+			}
+			return token, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown gcert authentication method %q (expected one of %s)", method,
+			strings.Join(rpc.AuthMethods, ", "))
 	}
 }
 
@@ -75,8 +500,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError("Unable to find the home directory", err)
 		}
 
 		// Search config in home directory with name ".gcli" (without extension).
@@ -85,10 +509,67 @@ func initConfig() {
 	}
 
 	viper.SetEnvPrefix("VCLI")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	configErr := viper.ReadInConfig()
+
+	applyProfile()
+	configureLogging()
+
+	logger := logging.L().Named("config")
+	if _, ok := configErr.(viper.ConfigFileNotFoundError); configErr != nil && !ok {
+		logger.Warn("unable to read config file", "error", configErr)
+	} else if configErr == nil {
+		logger.Debug("using config file", "path", viper.ConfigFileUsed())
+	}
+	if name := viper.GetString("profile"); name != "" {
+		logger.Debug("applied profile", "profile", name)
+	}
+
+	// Check the output format up front rather than after a command has already made changes
+	format := viper.GetString("output")
+	for _, f := range output.Formats {
+		if format == f {
+			return
+		}
+	}
+	exitWithError("Invalid --output", fmt.Errorf("unknown output format %q (expected one of %s)", format,
+		strings.Join(output.Formats, ", ")))
+}
+
+// configureLogging sets the default logger to write to stderr at the level given by the log-level setting, or by the
+// number of -v flags if it isn't set.
+func configureLogging() {
+	level := viper.GetString("log-level")
+	if level == "" {
+		level = logging.Verbosity(verbose)
+	}
+
+	logger, err := logging.New(os.Stderr, level, viper.GetString("log-format"))
+	if err != nil {
+		exitWithError("Error configuring logging", err)
+	}
+	logging.SetDefault(logger)
+}
+
+// applyProfile merges the settings of the selected profile (profiles.[name] in the config file) over the top-level
+// config file settings. Flags and environment variables still take precedence over the profile settings.
+func applyProfile() {
+	name := viper.GetString("profile")
+	if name == "" {
+		return
+	}
+
+	settings := viper.GetStringMap("profiles." + name)
+	if len(settings) == 0 {
+		exitWithError("Error applying profile", failure.New(failure.NotFound,
+			fmt.Errorf("profile %s was not found in the config file", name),
+			"check the profile name, or add it under the profiles key of the config file"))
+	}
+
+	if err := viper.MergeConfigMap(settings); err != nil {
+		exitWithError("Error applying profile", err)
 	}
 }
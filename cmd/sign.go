@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/cert"
+
+	"github.com/spf13/cobra"
+)
+
+var signKeyType string
+var signKeyBits int
+var signAltNames []string
+var signTTL string
+var signDir string
+
+// signCmd represents the sign command
+var signCmd = &cobra.Command{
+	Use:   "sign [role] [common name]",
+	Args:  cobra.ExactArgs(2),
+	Short: "Signs a locally generated key with the Vault PKI secrets engine",
+	Long: `Generates a new private key and certificate signing request locally and sends only the CSR to the PKI secrets
+engine to be signed using the given role. The private key never leaves this host. The locally generated key, the
+signed certificate, and the CA chain are written to the output directory.
+
+Supported key types are ec and rsa.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewPKISign(args[0], args[1], signAltNames, signTTL, signDir)
+	},
+}
+
+func init() {
+	pkiCmd.AddCommand(signCmd)
+
+	signCmd.Flags().StringVar(&signKeyType, "key-type", "ec", "Type of private key to generate (ec, rsa)")
+	signCmd.Flags().IntVar(&signKeyBits, "key-bits", 0, "Size of the private key (defaults to 256 for ec and 2048 for rsa)")
+	signCmd.Flags().StringSliceVar(&signAltNames, "alt-names", []string{}, "Additional subject alternative names")
+	signCmd.Flags().StringVar(&signTTL, "ttl", "", "Requested certificate TTL (defaults to the role TTL)")
+	signCmd.Flags().StringVar(&signDir, "dir", ".", "Directory to write the certificate files to")
+}
+
+func NewPKISign(role string, commonName string, altNames []string, ttl string, dir string) {
+	key, err := cert.NewKey(signKeyType, signKeyBits)
+	if err != nil {
+		exitWithError("Error generating private key", err)
+	}
+
+	csr, err := cert.NewCSR(key, commonName, altNames)
+	if err != nil {
+		exitWithError("Error generating certificate signing request", err)
+	}
+
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	certificate, err := vaultClient.SignCertificate(pkiMount, role, csr, commonName, altNames, ttl)
+	if err != nil {
+		exitWithError("Error signing certificate", err)
+	}
+
+	certificate.PrivateKey, err = cert.EncodePrivateKey(key)
+	if err != nil {
+		exitWithError("Error encoding private key", err)
+	}
+
+	paths, err := cert.Write(dir, certificate)
+	if err != nil {
+		exitWithError("Error writing certificate", err)
+	}
+
+	printResult(pkiCertificateResult{
+		SerialNumber: certificate.SerialNumber,
+		Files:        paths,
+	})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"github.com/jmgilman/gcli/files"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var sshSignOut string
+
+// sshSignCmd represents the sign command of the ssh command
+var sshSignCmd = &cobra.Command{
+	Use:   "sign [role] [public key]",
+	Args:  cobra.ExactArgs(2),
+	Short: "Signs an SSH public key with the Vault SSH secrets engine",
+	Long: `Signs the given SSH public key file using the given role and writes the signed certificate next to it, following
+the OpenSSH naming convention (i.e. id_ed25519.pub is signed to id_ed25519-cert.pub).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := newContext(cmd)
+		defer cancel()
+
+		NewSSHSign(ctx, args[0], args[1], sshSignOut)
+	},
+}
+
+func init() {
+	sshCmd.AddCommand(sshSignCmd)
+
+	sshSignCmd.Flags().StringVar(&sshSignOut, "out", "", "File to write the signed certificate to (defaults to [public key]-cert.pub)")
+}
+
+func NewSSHSign(ctx context.Context, role string, publicKey string, out string) {
+	key, err := ioutil.ReadFile(publicKey)
+	if err != nil {
+		exitWithError("Error reading public key", err)
+	}
+
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	signedKey, err := vaultClient.SignPubKeyWithContext(ctx, sshMount, role, key)
+	if err != nil {
+		exitWithError("Error signing public key", err)
+	}
+
+	if out == "" {
+		out = strings.TrimSuffix(publicKey, ".pub") + "-cert.pub"
+	}
+
+	if _, err := files.WriteAtomic(out, []byte(signedKey), 0644); err != nil {
+		exitWithError("Error writing signed certificate", err)
+	}
+
+	printResult(sshSignResult{
+		Role:        role,
+		Certificate: out,
+		SignedKey:   signedKey,
+	})
+}
+
+// sshSignResult is the result of the ssh sign command.
+type sshSignResult struct {
+	Role        string `json:"role"`
+	Certificate string `json:"certificate"`
+	SignedKey   string `json:"signed_key"`
+}
+
+func (r sshSignResult) Rows() ([]string, [][]string) {
+	return []string{"ROLE", "CERTIFICATE"}, [][]string{{r.Role, r.Certificate}}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/vault/client"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Args:  cobra.NoArgs,
+	Short: "Shows the status of the configured Vault server or cluster",
+	Long: `Shows whether the configured Vault server is available. When the addresses of an HA cluster are configured with
+--vault-addresses, the health of each node is shown in order of preference (the active node, then standbys, then
+unavailable nodes) along with the node serving requests.
+
+gcli exits with code 5 if the server or every node of the cluster is sealed or uninitialized, or with the exit code of
+the error if one couldn't be reached.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := newContext(cmd)
+		defer cancel()
+
+		NewStatus(ctx, viper.GetStringSlice("vault-addresses"))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+func NewStatus(ctx context.Context, addresses []string) {
+	vaultClient, err := newVaultClient()
+	if err != nil && (len(addresses) == 0 || !errors.Is(err, client.ErrNoAvailableNodes)) {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	if len(addresses) == 0 {
+		node := nodeStatus{Address: vaultClient.Address(), State: "available"}
+		exitCode := 0
+
+		available, err := vaultClient.AvailableWithContext(ctx)
+		switch {
+		case err != nil:
+			node.State = "unreachable"
+			node.Error = err.Error()
+			exitCode = failure.ExitCode(err)
+		case !available:
+			node.State = "sealed or uninitialized"
+			exitCode = failure.Sealed.ExitCode()
+		}
+
+		result := statusResult{Nodes: []nodeStatus{node}}
+		if exitCode == 0 {
+			result.ServedBy = node.Address
+		}
+
+		printResult(result)
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+		return
+	}
+
+	// The nodes were already probed when the client was configured
+	health := vaultClient.Nodes()
+
+	var result statusResult
+	var nodeErr error
+	for _, node := range health {
+		status := nodeStatus{Address: node.Address}
+		switch {
+		case node.Err != nil:
+			status.State = "unreachable"
+			status.Error = node.Err.Error()
+			if nodeErr == nil {
+				nodeErr = node.Err
+			}
+		case node.Active():
+			status.State = "active"
+		case node.Available():
+			status.State = "standby"
+		case node.Sealed:
+			status.State = "sealed"
+		default:
+			status.State = "uninitialized"
+		}
+		result.Nodes = append(result.Nodes, status)
+	}
+
+	// No node serves requests when none of them are available
+	if err == nil {
+		result.ServedBy = vaultClient.Address()
+	}
+
+	printResult(result)
+	if err == nil {
+		return
+	}
+
+	// The cluster is only reported as sealed when every node responded as sealed or uninitialized
+	if nodeErr != nil {
+		os.Exit(failure.ExitCode(nodeErr))
+	}
+	os.Exit(failure.Sealed.ExitCode())
+}
+
+// nodeStatus is the status of a single Vault node.
+type nodeStatus struct {
+	Address string `json:"address"`
+	State   string `json:"state"`
+	Error   string `json:"error,omitempty"`
+}
+
+// statusResult is the result of the status command. ServedBy is the address of the node which requests are sent to,
+// and is empty if none of the nodes are available.
+type statusResult struct {
+	Nodes    []nodeStatus `json:"nodes"`
+	ServedBy string       `json:"served_by"`
+}
+
+func (r statusResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r.Nodes))
+	for i, node := range r.Nodes {
+		state := node.State
+		if node.Error != "" {
+			state = fmt.Sprintf("%s (%s)", state, node.Error)
+		}
+
+		serving := ""
+		if node.Address == r.ServedBy {
+			serving = "*"
+		}
+
+		rows[i] = []string{node.Address, state, serving}
+	}
+	return []string{"ADDRESS", "STATE", "SERVING"}, rows
+}
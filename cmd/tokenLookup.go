@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/output"
+
+	"github.com/spf13/cobra"
+)
+
+// tokenLookupCmd represents the lookup command of the token command
+var tokenLookupCmd = &cobra.Command{
+	Use:   "lookup",
+	Args:  cobra.NoArgs,
+	Short: "Shows the properties of the configured Vault token",
+	Long: `Looks up the configured Vault token and shows its properties (i.e. policies and TTL). The token itself is never
+included in the output.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewTokenLookup()
+	},
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenLookupCmd)
+}
+
+func NewTokenLookup() {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	data, err := vaultClient.ReadSecret("auth/token/lookup-self")
+	if err != nil {
+		exitWithError("Error looking up token", err)
+	}
+
+	// The ID is the token itself
+	delete(data, "id")
+
+	printResult(output.KeyValues(data))
+}
@@ -0,0 +1,126 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/base64"
+	"github.com/jmgilman/gcli/files"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var transitMount string
+var transitIn string
+var transitOut string
+var transitBase64 bool
+
+// transitCmd represents the transit command
+var transitCmd = &cobra.Command{
+	Use:   "transit",
+	Short: "Commands for encrypting, decrypting, and signing data with the Vault transit secrets engine",
+	Long: `Provides commands for working with data using keys stored in the Vault transit secrets engine. Input is read
+from stdin (or the file given with --in) and output is written to stdout (or the file given with --out).
+
+Large files can be encrypted with --envelope, which encrypts the data locally in chunks using a data key generated and
+wrapped by the transit key. Only the wrapped data key is stored with the data, so it can't be decrypted without access
+to the transit key.`,
+}
+
+func init() {
+	rootCmd.AddCommand(transitCmd)
+
+	transitCmd.PersistentFlags().StringVar(&transitMount, "mount", "transit", "Mount point of the transit secrets engine")
+	transitCmd.PersistentFlags().StringVar(&transitIn, "in", "", "File to read input from (defaults to stdin)")
+	transitCmd.PersistentFlags().StringVar(&transitOut, "out", "", "File to write output to (defaults to stdout)")
+	transitCmd.PersistentFlags().BoolVar(&transitBase64, "base64", false, "Treat plaintext input and output as base64 encoded")
+}
+
+// openInput returns the file at the given path for reading, or stdin if the path is empty or "-".
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// outputFile is the destination of transit output. Close commits the output, while Abort discards it so a failed
+// command never leaves a partially written file behind.
+type outputFile interface {
+	io.Writer
+	Close() error
+	Abort()
+}
+
+// openOutput returns the file at the given path for writing, or stdout if the path is empty or "-". Files are written
+// atomically and created readable only by the current user.
+func openOutput(path string) (outputFile, error) {
+	if path == "" || path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return files.Create(path, 0600)
+}
+
+// readInput reads all of the transit input. If decode is true and --base64 was given, the input is base64 decoded.
+func readInput(decode bool) ([]byte, error) {
+	in, err := openInput(transitIn)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer in.Close()
+
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if decode && transitBase64 {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
+
+// writeOutput writes the given data to the transit output. If encode is true and --base64 was given, the data is
+// base64 encoded.
+func writeOutput(data []byte, encode bool) error {
+	out, err := openOutput(transitOut)
+	if err != nil {
+		return err
+	}
+
+	if encode && transitBase64 {
+		data = []byte(base64.StdEncoding.EncodeToString(data) + "\n")
+	}
+
+	if _, err := out.Write(data); err != nil {
+		out.Abort()
+		return err
+	}
+
+	return out.Close()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (nopWriteCloser) Abort() {}
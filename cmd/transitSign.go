@@ -0,0 +1,56 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// transitSignCmd represents the sign command of the transit command
+var transitSignCmd = &cobra.Command{
+	Use:   "sign [key]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Signs data with a transit key",
+	Long:  `Signs the input with the given transit key and outputs the resulting signature (i.e. vault:v1:...).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewTransitSign(args[0])
+	},
+}
+
+func init() {
+	transitCmd.AddCommand(transitSignCmd)
+}
+
+func NewTransitSign(key string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	input, err := readInput(true)
+	if err != nil {
+		exitWithError("Error reading input", err)
+	}
+
+	signature, err := vaultClient.TransitSign(transitMount, key, input)
+	if err != nil {
+		exitWithError("Error signing input", err)
+	}
+
+	if err := writeOutput([]byte(signature+"\n"), false); err != nil {
+		exitWithError("Error writing output", err)
+	}
+}
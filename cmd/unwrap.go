@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/output"
+	"github.com/jmgilman/gcli/vault/client"
+
+	"github.com/spf13/cobra"
+)
+
+var unwrapExpectedPath string
+
+// unwrapCmd represents the unwrap command
+var unwrapCmd = &cobra.Command{
+	Use:   "unwrap [token]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Unwraps data from a Vault response wrapping token",
+	Long: `Validates the given response wrapping token and prints the data it wraps. Before unwrapping, the token is looked
+up to ensure it was created by the expected path (by default the path used by "gcli wrap"). A token with any other
+creation path may have been tampered with and is refused.
+
+Data wrapped as {"value": "<input>"} is printed as-is, any other data is printed as JSON.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewUnwrap(args[0], unwrapExpectedPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unwrapCmd)
+
+	unwrapCmd.Flags().StringVar(&unwrapExpectedPath, "expected-path", client.WrapPath, "Expected creation path of the token")
+}
+
+func NewUnwrap(token string, expectedPath string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	info, err := vaultClient.LookupWrappingToken(token)
+	if err != nil {
+		exitWithError("Error validating wrapping token", err)
+	}
+
+	if info.CreationPath != expectedPath {
+		exitWithError("Refusing to unwrap token", failure.New(failure.Unknown,
+			fmt.Errorf("the token was created by %s (expected %s)", info.CreationPath, expectedPath),
+			"the token may have been tampered with, request a new one from its sender"))
+	}
+
+	data, err := vaultClient.Unwrap(token)
+	if err != nil {
+		exitWithError("Error unwrapping token", err)
+	}
+
+	printResult(unwrapResult(data))
+}
+
+// unwrapResult is the result of the unwrap command. As a table, data which only has a value (i.e. it was wrapped from
+// plain text) is rendered as the bare value.
+type unwrapResult map[string]interface{}
+
+func (r unwrapResult) Rows() ([]string, [][]string) {
+	if value, ok := r["value"].(string); ok && len(r) == 1 {
+		return []string{}, [][]string{{value}}
+	}
+	return output.KeyValues(r).Rows()
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/jmgilman/gcli/output"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var verifySignature string
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [key]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Verifies a signature with a transit key",
+	Long: `Verifies the given signature of the input with the given transit key. The command exits with a non-zero status
+if the signature is invalid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewTransitVerify(args[0], verifySignature)
+	},
+}
+
+func init() {
+	transitCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&verifySignature, "signature", "", "Signature to verify (i.e. vault:v1:...)")
+	if err := verifyCmd.MarkFlagRequired("signature"); err != nil {
+		exitWithError("Error marking flag as required", err)
+	}
+}
+
+func NewTransitVerify(key string, signature string) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	input, err := readInput(true)
+	if err != nil {
+		exitWithError("Error reading input", err)
+	}
+
+	valid, err := vaultClient.TransitVerify(transitMount, key, input, signature)
+	if err != nil {
+		exitWithError("Error verifying signature", err)
+	}
+
+	printResult(verifyResult{
+		Key:   key,
+		Valid: valid,
+	})
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+// verifyResult is the result of the verify command.
+type verifyResult struct {
+	Key   string `json:"key"`
+	Valid bool   `json:"valid"`
+}
+
+func (r verifyResult) Rows() ([]string, [][]string) {
+	return []string{"KEY", "VALID"}, [][]string{{r.Key, output.Value(r.Valid)}}
+}
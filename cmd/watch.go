@@ -0,0 +1,262 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	gcert "github.com/jmgilman/gcert/proto"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/manifest"
+	"github.com/jmgilman/gcli/output"
+	"github.com/jmgilman/gcli/watch"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var watchFile string
+var watchState string
+var watchInterval time.Duration
+var watchJitter time.Duration
+var watchRenewBefore string
+var watchOnce bool
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Args:  cobra.NoArgs,
+	Short: "Renews the certificates listed in a manifest before they expire",
+	Long: `Runs in the foreground, periodically checking the certificates listed in the given manifest (see the request
+command for the format). Certificates with an output directory are checked on disk, the rest are checked in Vault. Once
+a certificate is within the renewal window (--renew-before), it's renewed through the gcert service, written to its
+output directory, and its deploy hooks are run. If Vault already holds a newer certificate (i.e. it was renewed by
+another host), it's written out without asking the gcert service.
+
+Failed renewals are retried with a backoff starting at one hour and doubling up to a day. The outcome of every check is
+saved to the state file (defaults to the manifest path with a .state extension) so the backoff survives restarts.
+
+Checks are repeated every --interval with up to --jitter added so hosts don't renew in lockstep. Sending SIGHUP reloads
+the manifest and checks it immediately. SIGINT or SIGTERM stops the watcher once the checks in progress finish, skipping
+the checks which haven't started yet, so a renewal is never abandoned half way; a second signal exits immediately. With
+--once, the certificates are checked a single time and a summary is shown. It exits with the exit code of the first
+failed check, or 11 if a deploy hook failed, so failures can be reported by cron.`,
+	// Interrupting the watcher stops it once the checks in progress finish
+	Annotations: map[string]string{handlesInterrupts: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		renewBefore, err := parseDuration(watchRenewBefore)
+		if err != nil {
+			exitWithError("Invalid --renew-before", err)
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		NewCertificateWatch(ctx, watchFile, watchStatePath(), renewBefore, watchOnce)
+	},
+}
+
+func init() {
+	certCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVarP(&watchFile, "file", "f", "", "Manifest listing the certificates to watch")
+	watchCmd.Flags().StringVar(&watchState, "state", "", "File the state of every certificate is saved to (defaults to the manifest path with a .state extension)")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 12*time.Hour, "Time between checks")
+	watchCmd.Flags().DurationVar(&watchJitter, "jitter", 30*time.Minute, "Maximum random delay added to the time between checks")
+	watchCmd.Flags().StringVar(&watchRenewBefore, "renew-before", "30d", "Renew certificates expiring within the given window")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "Check the certificates once and exit")
+	if err := watchCmd.MarkFlagRequired("file"); err != nil {
+		exitWithError("Error marking flag as required", err)
+	}
+}
+
+func NewCertificateWatch(ctx context.Context, path string, statePath string, renewBefore time.Duration, once bool) {
+	logger := logging.L().Named("watch")
+
+	state, err := watch.LoadState(statePath)
+	if err != nil {
+		exitWithError("Error loading state", err)
+	}
+
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	w := &watch.Watcher{
+		Source: vaultClient,
+		Renew: func(ctx context.Context, g manifest.Group) ([]string, error) {
+			domains, problems := validateDomains(g.Domains)
+			if len(problems) > 0 {
+				return []string{}, domainsError(problems)
+			}
+
+			conn, err := dialGcert(ctx)
+			if err != nil {
+				return []string{}, err
+			}
+			defer conn.Close()
+
+			paths, err := requestCertificate(ctx, gcert.NewCertificateServiceClient(conn), domains,
+				gcertEndpoint(g.Endpoint))
+			if err != nil {
+				return []string{}, err
+			}
+			return vaultPathDomains(paths), nil
+		},
+		RenewBefore: renewBefore,
+		State:       state,
+	}
+	load := func() (*manifest.Manifest, error) {
+		return manifest.Load(path)
+	}
+
+	// The first SIGINT or SIGTERM stops the watcher once the checks in progress finish, the second exits immediately
+	stop := make(chan struct{})
+	reload := make(chan struct{}, 1)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	go func() {
+		stopping := false
+		for sig := range signals {
+			switch {
+			case sig == syscall.SIGHUP:
+				logger.Info("reloading manifest")
+				// Reloads requested while checking are combined into one
+				select {
+				case reload <- struct{}{}:
+				default:
+				}
+			case stopping:
+				fmt.Fprintln(os.Stderr, "Interrupted")
+				os.Exit(130)
+			default:
+				logger.Warn("stopping once the checks in progress finish", "signal", sig.String())
+				stopping = true
+				close(stop)
+			}
+		}
+	}()
+
+	if once {
+		m, err := load()
+		if err != nil {
+			exitWithError("Error loading manifest", err)
+		}
+
+		results := make(watchResult, len(m.Certificates))
+		checked := make([]bool, len(m.Certificates))
+		manifest.Run(ctx, m.Certificates, m.Workers, func(ctx context.Context, i int, g manifest.Group) error {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+			results[i] = w.Check(ctx, g)
+			checked[i] = true
+			return nil
+		})
+		if err := state.Save(); err != nil {
+			exitWithError("Error saving state", err)
+		}
+
+		reported := watchResult{}
+		for i, r := range results {
+			if checked[i] {
+				reported = append(reported, r)
+			}
+		}
+		printResult(reported)
+
+		if err := watchFailure(reported); err != nil {
+			os.Exit(failure.ExitCode(err))
+		}
+		if len(reported) < len(results) {
+			fmt.Fprintln(os.Stderr, "Interrupted")
+			os.Exit(130)
+		}
+		return
+	}
+
+	logger.Info("watching certificates", "manifest", path, "interval", watchInterval, "renew_before", renewBefore)
+	err = w.Run(ctx, load, watchInterval, watchJitter, reload, stop, func(results []watch.Result) {
+		for _, r := range results {
+			args := []interface{}{"name", r.Name, "action", r.Action, "serial_number", r.SerialNumber,
+				"not_after", r.NotAfter}
+			switch r.Action {
+			case watch.Failed:
+				logger.Error("certificate check failed", append(args, "error", r.Error)...)
+			case watch.BackingOff:
+				logger.Warn("certificate renewal backing off", append(args, "error", r.Error)...)
+			default:
+				logger.Info("certificate checked", append(args, "files", len(r.Files))...)
+			}
+		}
+	})
+	if err != nil {
+		exitWithError("Error loading manifest", err)
+	}
+	logger.Info("stopped watching certificates")
+}
+
+// watchFailure returns the error of the given results a --once run exits with: a deploy hook failure if any hook failed,
+// otherwise the error of the first failed check. It returns nil if every check succeeded.
+func watchFailure(results []watch.Result) error {
+	var failed error
+	for _, r := range results {
+		switch r.Action {
+		case watch.HookFailed:
+			return r.Err
+		case watch.Failed:
+			if failed == nil {
+				failed = r.Err
+			}
+		}
+	}
+	return failed
+}
+
+// watchStatePath returns the path to the state file given by --state, defaulting to the manifest path with a .state
+// extension.
+func watchStatePath() string {
+	if watchState != "" {
+		return watchState
+	}
+	return watchFile + ".state"
+}
+
+// watchResult is the result of the watch command with --once.
+type watchResult []watch.Result
+
+func (r watchResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r))
+	for i, c := range r {
+		notAfter := ""
+		if !c.NotAfter.IsZero() {
+			notAfter = c.NotAfter.Format(time.RFC3339)
+		}
+		rows[i] = []string{c.Name, c.Action, c.SerialNumber, notAfter, output.Value(len(c.Files)), c.Error}
+	}
+	return []string{"NAME", "ACTION", "SERIAL", "NOT AFTER", "FILES CHANGED", "ERROR"}, rows
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var wrapTTL string
+var wrapIn string
+
+// wrapCmd represents the wrap command
+var wrapCmd = &cobra.Command{
+	Use:   "wrap",
+	Args:  cobra.NoArgs,
+	Short: "Wraps data in a single-use Vault response wrapping token",
+	Long: `Wraps the input in a single-use response wrapping token and prints the token. The input is read from stdin (or
+the file given with --in) and may be a JSON object, otherwise it's wrapped as {"value": "<input>"}.
+
+The token can be handed to someone else who can retrieve the data exactly once using "gcli unwrap". This is useful for
+securely handing off AppRole secret IDs, passwords, etc.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		NewWrap(wrapIn, wrapTTL)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(wrapCmd)
+
+	wrapCmd.Flags().StringVar(&wrapTTL, "ttl", "30m", "How long the wrapping token is valid for")
+	wrapCmd.Flags().StringVar(&wrapIn, "in", "", "File to read input from (defaults to stdin)")
+}
+
+func NewWrap(path string, ttl string) {
+	in, err := openInput(path)
+	if err != nil {
+		exitWithError("Error opening input", err)
+	}
+	defer in.Close()
+
+	input, err := ioutil.ReadAll(in)
+	if err != nil {
+		exitWithError("Error reading input", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(input, &data); err != nil {
+		data = map[string]interface{}{
+			"value": strings.TrimSuffix(string(input), "\n"),
+		}
+	}
+
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	info, err := vaultClient.Wrap(data, ttl)
+	if err != nil {
+		exitWithError("Error wrapping data", err)
+	}
+
+	printResult(wrapResult{
+		Token:        info.Token,
+		TTL:          info.TTL,
+		CreationTime: info.CreationTime.Format(time.RFC3339),
+		CreationPath: info.CreationPath,
+	})
+}
+
+// wrapResult is the result of the wrap command. As a table only the token is rendered so it can be captured by scripts
+// as-is.
+type wrapResult struct {
+	Token        string `json:"token"`
+	TTL          int    `json:"ttl"`
+	CreationTime string `json:"creation_time"`
+	CreationPath string `json:"creation_path"`
+}
+
+func (r wrapResult) Rows() ([]string, [][]string) {
+	return []string{}, [][]string{{r.Token}}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/hook"
+	"github.com/jmgilman/gcli/output"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var writeDir string
+var writeFormat string
+var writePasswordFile string
+var writeFriendlyName string
+
+// writeCmd represents the write command
+var writeCmd = &cobra.Command{
+	Use:   "write [domain1] [domain2] ...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Writes the certificates stored in Vault for the given domains to the local filesystem",
+	Long: `Reads the certificates written to Vault by the gcert service for each of the given domains and writes them to
+[dir]/[domain]. The format of the files is given by --format:
+
+  pem       the certificate, private key, issuer chain, and full chain in separate files (the default)
+  combined  the certificate, issuer chain, and private key in a single combined.pem (i.e. for HAProxy or UniFi)
+  der       the DER encoded certificate and PKCS #8 private key in cert.der and privkey.der
+  p12       the certificate, issuer chain, and private key in a password protected PKCS #12 file, cert.p12
+
+The PKCS #12 password is read from --password-file ("-" reads it from stdin). The certificate and private key are given
+the friendly name from --friendly-name (defaulting to the domain), which Java uses as the alias of the key when the file
+is loaded as a PKCS12 keystore. Both flags are rejected for the other formats.
+
+Deploy hooks given with the --hook flags are run for each certificate whose files changed, i.e. to reload the services
+using it. The certificate is passed to hooks in the GCLI_DOMAIN, GCLI_SERIAL, GCLI_DIR, GCLI_FORMAT, and GCLI_FILES
+environment variables, along with GCLI_CERT, GCLI_KEY, GCLI_CHAIN, and GCLI_FULLCHAIN for the pem format. If a hook
+fails or times out, the remaining certificates are still written and gcli exits with code 11.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		hooks, err := deployHooks()
+		if err != nil {
+			exitWithError("Invalid deploy hook", err)
+		}
+
+		opts := cert.WriteOptions{Format: writeFormat, FriendlyName: writeFriendlyName}
+		if !validFormat(writeFormat) {
+			exitWithError("Invalid --format", fmt.Errorf("unknown format %q (expected one of %s)", writeFormat,
+				strings.Join(cert.Formats, ", ")))
+		}
+		if writeFormat == cert.PKCS12 {
+			if writePasswordFile == "" {
+				exitWithError("Missing PKCS #12 password", fmt.Errorf("--password-file is required with --format p12"))
+			}
+			opts.Password, err = readPasswordFile(writePasswordFile)
+			if err != nil {
+				exitWithError("Error reading PKCS #12 password", err)
+			}
+		} else if writePasswordFile != "" || writeFriendlyName != "" {
+			exitWithError("Invalid flags", fmt.Errorf("--password-file and --friendly-name can only be used with --format p12"))
+		}
+
+		ctx, cancel := newContext(cmd)
+		defer cancel()
+
+		NewCertificateWrite(ctx, args, writeDir, opts, hooks)
+	},
+}
+
+func init() {
+	certCmd.AddCommand(writeCmd)
+
+	writeCmd.Flags().StringVar(&writeDir, "dir", ".", "Directory to write the certificates to")
+	writeCmd.Flags().StringVar(&writeFormat, "format", cert.PEM, "Format to write the certificates in ("+strings.Join(cert.Formats, ", ")+")")
+	writeCmd.Flags().StringVar(&writePasswordFile, "password-file", "", "File containing the PKCS #12 password, or - to read it from stdin")
+	writeCmd.Flags().StringVar(&writeFriendlyName, "friendly-name", "", "Friendly name of the certificate in the PKCS #12 file (defaults to the domain)")
+	addHookFlags(writeCmd)
+}
+
+func NewCertificateWrite(ctx context.Context, domains []string, dir string, opts cert.WriteOptions, hooks []hook.Hook) {
+	vaultClient, err := newVaultClient()
+	if err != nil {
+		exitWithError("Unable to configure Vault client", err)
+	}
+
+	var results certificateWriteResult
+	var hookErr error
+	for _, domain := range domains {
+		certificate, err := vaultClient.GetCertificate(domain)
+		if err != nil {
+			exitWithError("Error reading certificate for "+domain, err)
+		}
+
+		domainDir := filepath.Join(dir, domain)
+		paths, err := cert.WriteFormat(domainDir, certificate, opts)
+		if err != nil {
+			exitWithError("Error writing certificate for "+domain, err)
+		}
+		result := certificateFiles{
+			Domain: domain,
+			Files:  paths,
+		}
+
+		if len(hooks) > 0 {
+			result.Hooks = hookSkipped
+			if len(paths) > 0 {
+				result.Hooks = hookOK
+				err := hook.RunAll(ctx, hooks, hook.Context{
+					Domain:       domain,
+					Domains:      []string{domain},
+					SerialNumber: certificate.SerialNumber,
+					Output:       dir,
+					Dir:          domainDir,
+					Files:        paths,
+					Format:       opts.Format,
+				})
+				if err != nil {
+					result.Hooks = hookFailed
+					if hookErr == nil {
+						hookErr = err
+					}
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	printResult(results)
+	if hookErr != nil {
+		exitWithError("Error running deploy hooks", hookErr)
+	}
+}
+
+// validFormat returns true if the given format is one of cert.Formats.
+func validFormat(format string) bool {
+	for _, f := range cert.Formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// readPasswordFile returns the password in the file at the given path, or stdin if the path is -. A trailing newline is
+// removed.
+func readPasswordFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// The outcomes of running the deploy hooks for a certificate.
+const (
+	hookOK      = "ok"
+	hookSkipped = "unchanged"
+	hookFailed  = "failed"
+)
+
+// certificateFiles contains the files written for the certificate of a single domain.
+type certificateFiles struct {
+	Domain string   `json:"domain"`
+	Files  []string `json:"files"`
+	Hooks  string   `json:"hooks,omitempty"`
+}
+
+// certificateWriteResult is the result of the write command.
+type certificateWriteResult []certificateFiles
+
+func (r certificateWriteResult) Rows() ([]string, [][]string) {
+	rows := make([][]string, len(r))
+	for i, c := range r {
+		rows[i] = []string{c.Domain, output.Value(c.Files), c.Hooks}
+	}
+	return []string{"DOMAIN", "FILES", "HOOKS"}, rows
+}
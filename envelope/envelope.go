@@ -0,0 +1,239 @@
+// The envelope package implements streaming envelope encryption for large files. Each stream is encrypted with a
+// unique data key using AES-256-GCM and the data key itself is stored alongside the data in wrapped form (i.e. encrypted
+// by a Vault transit key), meaning the stream can only be decrypted by someone able to unwrap the data key.
+//
+// A stream consists of a header followed by one or more chunks:
+//
+//	header: magic (8 bytes) | wrapped key length (uint16) | wrapped key
+//	chunk:  final flag (1 byte) | ciphertext length (uint32) | ciphertext
+//
+// Each chunk is sealed with a nonce derived from its sequence number and final flag, so reordered, truncated, or
+// extended streams fail to decrypt.
+package envelope
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChunkSize is the maximum number of plaintext bytes sealed in a single chunk.
+const ChunkSize = 64 * 1024
+
+// magic identifies the start of an envelope stream and its format version.
+var magic = []byte("GCLIENV1")
+
+// ErrTruncated is returned when a stream ends before its final chunk.
+var ErrTruncated = errors.New("envelope stream is truncated")
+
+// Encrypt reads plaintext from the given reader and writes an envelope stream to the given writer. The plaintext is
+// encrypted with the given data key and the wrapped form of the data key is written to the stream header.
+func Encrypt(w io.Writer, r io.Reader, dataKey []byte, wrappedKey string) error {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	if err := writeHeader(w, wrappedKey); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, ChunkSize)
+	buf := make([]byte, ChunkSize)
+	for seq := uint64(0); ; seq++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		// A full chunk is only final if nothing follows it
+		final := err != nil
+		if !final {
+			if _, err := br.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		sealed := aead.Seal(nil, nonce(seq, final), buf[:n], nil)
+		if err := writeChunk(w, sealed, final); err != nil {
+			return err
+		}
+
+		if final {
+			return nil
+		}
+	}
+}
+
+// Decrypt reads an envelope stream from the given reader and writes the plaintext to the given writer. The wrapped data
+// key is read from the stream header and passed to the given unwrap function to retrieve the plaintext data key.
+func Decrypt(w io.Writer, r io.Reader, unwrap func(wrappedKey string) ([]byte, error)) error {
+	br := bufio.NewReader(r)
+	wrappedKey, err := readHeader(br)
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := unwrap(wrappedKey)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	for seq := uint64(0); ; seq++ {
+		sealed, final, err := readChunk(br)
+		if err != nil {
+			return err
+		}
+
+		plaintext, err := aead.Open(nil, nonce(seq, final), sealed, nil)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt chunk %d: %w", seq, err)
+		}
+
+		if _, err := w.Write(plaintext); err != nil {
+			return err
+		}
+
+		if final {
+			if _, err := br.Peek(1); err != io.EOF {
+				return fmt.Errorf("envelope stream has trailing data after the final chunk")
+			}
+			return nil
+		}
+	}
+}
+
+// Rewrap reads an envelope stream from the given reader and writes it to the given writer with the wrapped data key
+// replaced by the result of the given rewrap function. The encrypted chunks are copied as-is, so rotating the key that
+// wraps the data key never exposes the plaintext.
+func Rewrap(w io.Writer, r io.Reader, rewrap func(wrappedKey string) (string, error)) error {
+	br := bufio.NewReader(r)
+	wrappedKey, err := readHeader(br)
+	if err != nil {
+		return err
+	}
+
+	rewrapped, err := rewrap(wrappedKey)
+	if err != nil {
+		return err
+	}
+
+	if err := writeHeader(w, rewrapped); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, br)
+	return err
+}
+
+// WrappedKey returns the wrapped data key stored in the header of the envelope stream read from the given reader.
+func WrappedKey(r io.Reader) (string, error) {
+	return readHeader(r)
+}
+
+func newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	if len(dataKey) != 32 {
+		return nil, fmt.Errorf("data key must be 32 bytes, got %d", len(dataKey))
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the GCM nonce for the chunk with the given sequence number. Since every stream uses a unique data key,
+// a counter based nonce never repeats for the same key.
+func nonce(seq uint64, final bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n[3:11], seq)
+	if final {
+		n[11] = 1
+	}
+	return n
+}
+
+func writeHeader(w io.Writer, wrappedKey string) error {
+	if len(wrappedKey) > 0xFFFF {
+		return fmt.Errorf("wrapped key is too long")
+	}
+
+	header := make([]byte, 0, len(magic)+2+len(wrappedKey))
+	header = append(header, magic...)
+	header = append(header, byte(len(wrappedKey)>>8), byte(len(wrappedKey)))
+	header = append(header, wrappedKey...)
+
+	_, err := w.Write(header)
+	return err
+}
+
+func readHeader(r io.Reader) (string, error) {
+	prefix := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return "", fmt.Errorf("unable to read envelope header: %w", err)
+	}
+
+	if string(prefix[:len(magic)]) != string(magic) {
+		return "", fmt.Errorf("input is not an envelope stream")
+	}
+
+	wrappedKey := make([]byte, binary.BigEndian.Uint16(prefix[len(magic):]))
+	if _, err := io.ReadFull(r, wrappedKey); err != nil {
+		return "", fmt.Errorf("unable to read envelope header: %w", err)
+	}
+
+	return string(wrappedKey), nil
+}
+
+func writeChunk(w io.Writer, sealed []byte, final bool) error {
+	header := make([]byte, 5)
+	if final {
+		header[0] = 1
+	}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(sealed)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(sealed)
+	return err
+}
+
+func readChunk(r io.Reader) ([]byte, bool, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return []byte{}, false, ErrTruncated
+		}
+		return []byte{}, false, err
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > ChunkSize+16 {
+		return []byte{}, false, fmt.Errorf("envelope chunk is too large: %d bytes", length)
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return []byte{}, false, ErrTruncated
+		}
+		return []byte{}, false, err
+	}
+
+	return sealed, header[0] == 1, nil
+}
@@ -0,0 +1,114 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newDataKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newUnwrap(dataKey []byte) func(string) ([]byte, error) {
+	return func(wrappedKey string) ([]byte, error) {
+		if wrappedKey != "vault:v1:wrapped" {
+			return []byte{}, fmt.Errorf("unknown key: %s", wrappedKey)
+		}
+		return dataKey, nil
+	}
+}
+
+func encrypt(t *testing.T, dataKey []byte, plaintext []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encrypt(&buf, bytes.NewReader(plaintext), dataKey, "vault:v1:wrapped"); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	dataKey := newDataKey(t)
+	sizes := []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 17}
+
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("Test with %d bytes", size), func(t *testing.T) {
+			plaintext := make([]byte, size)
+			if _, err := rand.Read(plaintext); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			err := Decrypt(&out, bytes.NewReader(encrypt(t, dataKey, plaintext)), newUnwrap(dataKey))
+			assert.Nil(t, err)
+			assert.True(t, bytes.Equal(plaintext, out.Bytes()))
+		})
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	dataKey := newDataKey(t)
+	plaintext := bytes.Repeat([]byte("a"), 2*ChunkSize+10)
+	stream := encrypt(t, dataKey, plaintext)
+
+	t.Run("Test with a truncated stream", func(t *testing.T) {
+		var out bytes.Buffer
+		truncated := stream[:len(stream)-(10+16+5)]
+		err := Decrypt(&out, bytes.NewReader(truncated), newUnwrap(dataKey))
+		assert.Equal(t, ErrTruncated, err)
+	})
+
+	t.Run("Test with a modified chunk", func(t *testing.T) {
+		var out bytes.Buffer
+		modified := append([]byte{}, stream...)
+		modified[len(modified)-1] ^= 0xFF
+		err := Decrypt(&out, bytes.NewReader(modified), newUnwrap(dataKey))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test with trailing data", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Decrypt(&out, bytes.NewReader(append(stream, 0)), newUnwrap(dataKey))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test with the wrong data key", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Decrypt(&out, bytes.NewReader(stream), newUnwrap(newDataKey(t)))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test with invalid input", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Decrypt(&out, bytes.NewReader([]byte("not an envelope")), newUnwrap(dataKey))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRewrap(t *testing.T) {
+	dataKey := newDataKey(t)
+	stream := encrypt(t, dataKey, []byte("secret"))
+
+	var rewrapped bytes.Buffer
+	err := Rewrap(&rewrapped, bytes.NewReader(stream), func(wrappedKey string) (string, error) {
+		return "vault:v2:rewrapped", nil
+	})
+	assert.Nil(t, err)
+
+	wrappedKey, err := WrappedKey(bytes.NewReader(rewrapped.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, "vault:v2:rewrapped", wrappedKey)
+
+	var out bytes.Buffer
+	err = Decrypt(&out, &rewrapped, func(string) ([]byte, error) { return dataKey, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", out.String())
+}
@@ -0,0 +1,231 @@
+// The failure package contains the typed errors returned by gcli. Each error has a Kind which determines the exit code
+// gcli exits with, allowing scripts to react to specific failures, and a hint describing how to resolve it. Errors
+// returned from the Vault API and the gcert service are mapped to a Kind from their response and status codes.
+package failure
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/vault/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+)
+
+// Kind is the category of an Error.
+type Kind int
+
+const (
+	// Unknown is any error which doesn't fall into one of the other categories.
+	Unknown Kind = iota
+
+	// AuthFailed means the credentials or token given to Vault were rejected.
+	AuthFailed
+
+	// PermissionDenied means the Vault token doesn't grant access to the requested path.
+	PermissionDenied
+
+	// Sealed means the Vault server is sealed or uninitialized.
+	Sealed
+
+	// NotFound means the requested secret, mount, or role doesn't exist.
+	NotFound
+
+	// Network means the Vault server or gcert service couldn't be reached in time.
+	Network
+
+	// GcertRejected means the gcert service was unable to issue the requested certificates.
+	GcertRejected
+
+	// Expiring means a certificate expires within the window being checked (i.e. cert list --expiring-within).
+	Expiring
+
+	// Expired means a certificate has already expired.
+	Expired
+
+	// HookFailed means a deploy hook failed or timed out after the certificates were written.
+	HookFailed
+
+	// Stale means a TLS endpoint serves a different certificate than the one stored in Vault, i.e. the service wasn't
+	// reloaded after the certificate was renewed.
+	Stale
+
+	// Untrusted means a TLS endpoint serves a certificate chain which can't be verified for its server name, i.e. the
+	// intermediate certificate is missing or the certificate doesn't cover the name.
+	Untrusted
+)
+
+// exitCodes maps each Kind to the exit code gcli exits with. These are documented in the README and must not change.
+var exitCodes = map[Kind]int{
+	Unknown:          1,
+	AuthFailed:       3,
+	PermissionDenied: 4,
+	Sealed:           5,
+	NotFound:         6,
+	Network:          7,
+	GcertRejected:    8,
+	Expiring:         9,
+	Expired:          10,
+	HookFailed:       11,
+	Stale:            12,
+	Untrusted:        13,
+}
+
+// ExitCode returns the exit code gcli exits with for errors of the Kind.
+func (k Kind) ExitCode() int {
+	return exitCodes[k]
+}
+
+// String returns a short description of the Kind.
+func (k Kind) String() string {
+	switch k {
+	case AuthFailed:
+		return "authentication failed"
+	case PermissionDenied:
+		return "permission denied"
+	case Sealed:
+		return "Vault is sealed"
+	case NotFound:
+		return "not found"
+	case Network:
+		return "network error"
+	case GcertRejected:
+		return "gcert rejected the request"
+	case Expiring:
+		return "certificate expiring"
+	case Expired:
+		return "certificate expired"
+	case HookFailed:
+		return "deploy hook failed"
+	case Stale:
+		return "stale certificate served"
+	case Untrusted:
+		return "untrusted certificate served"
+	default:
+		return "error"
+	}
+}
+
+// Error is an error of a specific Kind along with a hint describing how it can be resolved.
+type Error struct {
+	Kind Kind
+	Err  error
+	Hint string
+}
+
+// New returns a new Error of the given Kind wrapping the given error.
+func New(kind Kind, err error, hint string) *Error {
+	return &Error{Kind: kind, Err: err, Hint: hint}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code gcli exits with for the given error. A nil error exits with zero.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return Classify(err).Kind.ExitCode()
+}
+
+// Classify returns the given error as an Error. If it isn't already one, it's mapped from a Vault API error, gRPC
+// status, or network error. Any other error is returned with the Unknown Kind.
+func Classify(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		return FromVault(err)
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return FromGRPC(err)
+	}
+
+	if network(err) {
+		return New(Network, err, "check the server address and that the server is running, or increase --timeout")
+	}
+
+	return New(Unknown, err, "")
+}
+
+// FromVault maps an error returned by the Vault API to an Error based on the response code.
+func FromVault(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) {
+		if network(err) {
+			return New(Network, err, "check --vault-address and that the Vault server is running, or increase --timeout")
+		}
+		return New(Unknown, err, "")
+	}
+
+	switch respErr.StatusCode {
+	case http.StatusUnauthorized:
+		return New(AuthFailed, err, "set a valid Vault token with --vault-token or VAULT_TOKEN")
+	case http.StatusForbidden:
+		return New(PermissionDenied, err, "the Vault token is invalid, expired, or its policies don't allow this; "+
+			"set a different token with --vault-token or VAULT_TOKEN")
+	case http.StatusNotFound:
+		return New(NotFound, err, "check the path, mount, and role are correct")
+	case http.StatusServiceUnavailable:
+		return New(Sealed, err, "unseal the Vault server, or list the other nodes of the cluster with --vault-addresses")
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return New(Network, err, "the Vault server is overloaded or unreachable, try again later")
+	default:
+		return New(Unknown, err, "")
+	}
+}
+
+// FromGRPC maps an error returned by the gcert service to an Error based on its gRPC status code. Errors which aren't
+// caused by the connection or credentials mean gcert rejected the request.
+func FromGRPC(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		if network(err) {
+			return New(Network, err, "check the gcert server address and that the server is running, or increase --timeout")
+		}
+		return New(Unknown, err, "")
+	}
+
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return New(Network, err, "check the gcert server address and that the server is running, or increase --timeout")
+	case codes.Unauthenticated:
+		return New(AuthFailed, err, "the gcert service rejected the credentials, set a valid Vault token with "+
+			"--vault-token or VAULT_TOKEN")
+	case codes.PermissionDenied:
+		return New(PermissionDenied, err, "the gcert service isn't allowed to issue certificates for these domains")
+	case codes.NotFound:
+		return New(NotFound, err, "check the domains are served by the gcert service")
+	case codes.Canceled:
+		return New(Unknown, err, "")
+	default:
+		return New(GcertRejected, err, "check the domains are valid and the logs of the gcert service")
+	}
+}
+
+// network returns true if the given error was caused by a failure to reach a server in time.
+func network(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
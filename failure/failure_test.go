@@ -0,0 +1,65 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind Kind
+	}{
+		{"Vault forbidden", &api.ResponseError{StatusCode: 403}, PermissionDenied},
+		{"Vault unauthorized", &api.ResponseError{StatusCode: 401}, AuthFailed},
+		{"Vault not found", &api.ResponseError{StatusCode: 404}, NotFound},
+		{"Vault sealed", &api.ResponseError{StatusCode: 503}, Sealed},
+		{"Vault bad request", &api.ResponseError{StatusCode: 400}, Unknown},
+		{"wrapped Vault error", fmt.Errorf("failed: %w", &api.ResponseError{StatusCode: 503}), Sealed},
+		{"gRPC unavailable", status.Error(codes.Unavailable, "unavailable"), Network},
+		{"gRPC invalid argument", status.Error(codes.InvalidArgument, "invalid domain"), GcertRejected},
+		{"gRPC internal", status.Error(codes.Internal, "ACME challenge failed"), GcertRejected},
+		{"gRPC permission denied", status.Error(codes.PermissionDenied, "denied"), PermissionDenied},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, Network},
+		{"timeout", context.DeadlineExceeded, Network},
+		{"typed", New(GcertRejected, errors.New("rejected"), "check the domains"), GcertRejected},
+		{"other", errors.New("failed"), Unknown},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			e := Classify(test.err)
+			assert.Equal(t, test.kind, e.Kind)
+			assert.Equal(t, test.err.Error(), e.Error())
+			assert.True(t, errors.Is(e, test.err) || e == test.err)
+			if test.kind != Unknown {
+				assert.NotEmpty(t, e.Hint)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, 1, ExitCode(errors.New("failed")))
+	assert.Equal(t, 5, ExitCode(&api.ResponseError{StatusCode: 503}))
+
+	// Every kind must have a distinct exit code
+	codes := map[int]Kind{}
+	for kind := Unknown; kind <= Untrusted; kind++ {
+		code := kind.ExitCode()
+		assert.NotZero(t, code)
+		if other, ok := codes[code]; ok {
+			t.Errorf("%s and %s have the same exit code", kind, other)
+		}
+		codes[code] = kind
+	}
+}
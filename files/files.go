@@ -0,0 +1,104 @@
+// The files package contains helpers for safely writing generated content (certificates, rendered templates, etc.) to
+// the local filesystem.
+package files
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomic writes the given data to the given path with the given file mode. The data is first written to a
+// temporary file in the same directory which is then renamed over the destination, ensuring readers never see a
+// partially written file. If the destination already contains the given data and has the given mode, nothing is
+// written. It returns whether the destination was changed.
+func WriteAtomic(path string, data []byte, mode os.FileMode) (bool, error) {
+	if Unchanged(path, data, mode) {
+		return false, nil
+	}
+
+	f, err := Create(path, mode)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Abort()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// File is a file being written atomically. Writes go to a temporary file in the same directory as the destination,
+// which only replaces the destination once the File is closed. Abort discards everything written instead, leaving the
+// destination untouched.
+type File struct {
+	*os.File
+	path string
+	mode os.FileMode
+	done bool
+}
+
+// Create returns a File which is renamed over the given path with the given file mode when it's closed.
+func Create(path string, mode os.FileMode) (*File, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return &File{}, err
+	}
+
+	return &File{File: tmp, path: path, mode: mode}, nil
+}
+
+// Close syncs the written data to disk and renames the File over its destination. The temporary file is removed if
+// this fails.
+func (f *File) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	err := f.File.Sync()
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.File.Name(), f.mode)
+	}
+	if err == nil {
+		err = os.Rename(f.File.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.File.Name())
+	}
+	return err
+}
+
+// Abort discards the File, removing its temporary file without touching the destination.
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
+
+// Unchanged returns true if the file at the given path already exists with the given contents and file mode.
+func Unchanged(path string, data []byte, mode os.FileMode) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != mode.Perm() {
+		return false
+	}
+
+	existing, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(existing, data)
+}
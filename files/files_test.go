@@ -0,0 +1,101 @@
+package files
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.conf")
+
+	t.Run("Test writing a new file", func(t *testing.T) {
+		changed, err := WriteAtomic(path, []byte("test"), 0600)
+		assert.Nil(t, err)
+		assert.True(t, changed)
+
+		contents, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("test"), contents)
+
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("Test writing identical contents", func(t *testing.T) {
+		changed, err := WriteAtomic(path, []byte("test"), 0600)
+		assert.Nil(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("Test changing the file mode", func(t *testing.T) {
+		changed, err := WriteAtomic(path, []byte("test"), 0644)
+		assert.Nil(t, err)
+		assert.True(t, changed)
+	})
+
+	t.Run("Test that no temporary files are left behind", func(t *testing.T) {
+		entries, err := ioutil.ReadDir(dir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
+
+func TestCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out")
+
+	if err := ioutil.WriteFile(path, []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test aborting", func(t *testing.T) {
+		f, err := Create(path, 0600)
+		assert.Nil(t, err)
+		_, err = f.Write([]byte("partial"))
+		assert.Nil(t, err)
+		f.Abort()
+
+		contents, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "original", string(contents))
+
+		entries, err := ioutil.ReadDir(dir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("Test closing", func(t *testing.T) {
+		f, err := Create(path, 0640)
+		assert.Nil(t, err)
+		_, err = f.Write([]byte("replaced"))
+		assert.Nil(t, err)
+
+		// The destination is only replaced once the file is closed
+		contents, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "original", string(contents))
+
+		assert.Nil(t, f.Close())
+		assert.Nil(t, f.Close())
+		f.Abort()
+
+		contents, err = ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "replaced", string(contents))
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+}
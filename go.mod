@@ -3,6 +3,8 @@ module github.com/jmgilman/gcli
 go 1.14
 
 require (
+	github.com/hashicorp/go-hclog v0.12.0
+	github.com/hashicorp/go-retryablehttp v0.6.2
 	github.com/hashicorp/vault v1.4.1
 	github.com/hashicorp/vault/api v1.0.5-0.20200317185738-82f498082f02
 	github.com/hashicorp/vault/sdk v0.1.14-0.20200429182704-29fce8f27ce4
@@ -14,5 +16,7 @@ require (
 	github.com/spf13/viper v1.6.3
 	github.com/stretchr/testify v1.5.1
 	golang.org/x/crypto v0.0.0-20200302210943-78000ba7a073
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a
 	google.golang.org/grpc v1.28.1
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,296 @@
+// The hook package contains the deploy hooks run after certificates are issued or written, i.e. to reload the services
+// using them. A hook runs a shell command, sends a signal to the process in a PID file, or reloads a systemd unit. Every
+// hook is given the details of the certificate through its environment and is stopped if it runs for too long.
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kinds of hooks.
+const (
+	// Command runs a command using the system shell.
+	Command = "command"
+
+	// Signal sends a signal to the process whose ID is in a PID file.
+	Signal = "signal"
+
+	// Systemd reloads a systemd unit with systemctl reload.
+	Systemd = "systemd"
+)
+
+// DefaultTimeout is how long a hook may run when no timeout is given.
+const DefaultTimeout = 30 * time.Second
+
+// Hook is a single deploy hook. Exactly one of Command, Signal, or Reload must be set. The PIDFile and Reload fields may
+// refer to the variables of the Context (i.e. /run/${GCLI_NAME}.pid).
+type Hook struct {
+	// Command is run using the system shell.
+	Command string `yaml:"command"`
+
+	// Signal is the name of the signal (i.e. HUP or SIGUSR1) sent to the process whose ID is in PIDFile.
+	Signal  string `yaml:"signal"`
+	PIDFile string `yaml:"pid-file"`
+
+	// Reload is the name of the systemd unit to reload.
+	Reload string `yaml:"reload"`
+
+	// Timeout is how long the hook may run. It defaults to DefaultTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Kind returns the kind of the hook (Command, Signal, or Systemd).
+func (h Hook) Kind() string {
+	switch {
+	case h.Command != "":
+		return Command
+	case h.Signal != "" || h.PIDFile != "":
+		return Signal
+	case h.Reload != "":
+		return Systemd
+	default:
+		return ""
+	}
+}
+
+// String returns a short description of the hook used in logs and errors.
+func (h Hook) String() string {
+	switch h.Kind() {
+	case Command:
+		return fmt.Sprintf("command %q", h.Command)
+	case Signal:
+		return fmt.Sprintf("signal %s to %s", h.Signal, h.PIDFile)
+	case Systemd:
+		return "systemctl reload " + h.Reload
+	default:
+		return "empty hook"
+	}
+}
+
+// Validate returns an error describing the first problem found with the hook.
+func (h Hook) Validate() error {
+	kinds := 0
+	for _, set := range []bool{h.Command != "", h.Signal != "" || h.PIDFile != "", h.Reload != ""} {
+		if set {
+			kinds++
+		}
+	}
+
+	switch {
+	case kinds == 0:
+		return fmt.Errorf("hook has no command, signal, or reload")
+	case kinds > 1:
+		return fmt.Errorf("hook must only have one of command, signal, or reload")
+	case h.Timeout < 0:
+		return fmt.Errorf("hook timeout must not be negative")
+	}
+
+	if h.Kind() == Signal {
+		if h.Signal == "" || h.PIDFile == "" {
+			return fmt.Errorf("signal hook needs both a signal and a pid-file")
+		}
+		if _, err := parseSignal(h.Signal); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Context is the certificate a hook is run for. It's passed to hooks as environment variables (see Env).
+type Context struct {
+	// Name is the name of the manifest group the certificate belongs to, if any.
+	Name string
+
+	// Domain is the domain of the certificate and Domains are all the domains that were requested with it.
+	Domain  string
+	Domains []string
+
+	// SerialNumber is the serial number of the certificate.
+	SerialNumber string
+
+	// Output is the directory certificates are written under and Dir is the directory this certificate was written
+	// to. Both are empty if the certificate wasn't written to disk.
+	Output string
+	Dir    string
+
+	// Files are the paths of the files which changed.
+	Files []string
+
+	// Format is the format the certificate was written in (see cert.Formats). It defaults to cert.PEM.
+	Format string
+}
+
+// Vars returns the variables of the Context: GCLI_NAME, GCLI_DOMAIN, GCLI_DOMAINS (comma separated), GCLI_SERIAL,
+// GCLI_OUTPUT, GCLI_DIR, GCLI_FILES (space separated), GCLI_FORMAT, and for PEM certificates the paths of the files in
+// GCLI_DIR: GCLI_CERT, GCLI_KEY, GCLI_CHAIN, and GCLI_FULLCHAIN.
+func (c Context) Vars() map[string]string {
+	format := c.Format
+	if format == "" {
+		format = cert.PEM
+	}
+
+	vars := map[string]string{
+		"GCLI_NAME":      c.Name,
+		"GCLI_DOMAIN":    c.Domain,
+		"GCLI_DOMAINS":   strings.Join(c.Domains, ","),
+		"GCLI_SERIAL":    c.SerialNumber,
+		"GCLI_OUTPUT":    c.Output,
+		"GCLI_DIR":       c.Dir,
+		"GCLI_FILES":     strings.Join(c.Files, " "),
+		"GCLI_FORMAT":    format,
+		"GCLI_CERT":      "",
+		"GCLI_KEY":       "",
+		"GCLI_CHAIN":     "",
+		"GCLI_FULLCHAIN": "",
+	}
+
+	if c.Dir != "" && format == cert.PEM {
+		vars["GCLI_CERT"] = filepath.Join(c.Dir, cert.CertificateFile)
+		vars["GCLI_KEY"] = filepath.Join(c.Dir, cert.PrivateKeyFile)
+		vars["GCLI_CHAIN"] = filepath.Join(c.Dir, cert.ChainFile)
+		vars["GCLI_FULLCHAIN"] = filepath.Join(c.Dir, cert.FullChainFile)
+	}
+
+	return vars
+}
+
+// Env returns the environment hooks are run with: the environment of gcli along with the variables of the Context.
+func (c Context) Env() []string {
+	env := os.Environ()
+	for name, value := range c.Vars() {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// expand replaces references to the variables of the Context in the given string.
+func (c Context) expand(s string) string {
+	vars := c.Vars()
+	return os.Expand(s, func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	})
+}
+
+// Run runs the hook for the given certificate. The error returned if it fails, times out, or can't be started is a
+// failure.Error of the HookFailed Kind.
+func (h Hook) Run(ctx context.Context, c Context) error {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	switch h.Kind() {
+	case Command:
+		err = run(hookCtx, exec.Command("sh", "-c", h.Command), c)
+	case Signal:
+		err = h.sendSignal(c)
+	case Systemd:
+		err = run(hookCtx, exec.Command("systemctl", "reload", c.expand(h.Reload)), c)
+	default:
+		err = h.Validate()
+	}
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() == nil && errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return failure.New(failure.HookFailed, fmt.Errorf("deploy hook %s failed for %s: %w", h, c.Domain, err),
+		"the certificates were written, but the services using them may still have the previous certificates")
+}
+
+// sendSignal sends the signal of the hook to the process whose ID is in its PID file.
+func (h Hook) sendSignal(c Context) error {
+	sig, err := parseSignal(h.Signal)
+	if err != nil {
+		return err
+	}
+
+	path := c.expand(h.PIDFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return fmt.Errorf("%s doesn't contain a process ID", path)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
+
+// run runs the given command with the environment of the given Context until it exits or the context is done. The
+// output of the command is included in the error returned if it fails.
+func run(ctx context.Context, cmd *exec.Cmd, c Context) error {
+	var out strings.Builder
+	cmd.Env = c.Env()
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := start(cmd); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// The command may have started processes of its own which hold on to its output, so they're stopped too
+		kill(cmd)
+		err = <-done
+	}
+
+	if err != nil {
+		if output := strings.TrimSpace(out.String()); output != "" {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+		return err
+	}
+	return nil
+}
+
+// RunAll runs each of the given hooks in order for the given certificate. Every hook is run even if an earlier one
+// fails; the error of the first hook which failed is returned.
+func RunAll(ctx context.Context, hooks []Hook, c Context) error {
+	logger := logging.L().Named("hook")
+
+	var first error
+	for _, h := range hooks {
+		logger.Info("running deploy hook", "hook", h.String(), "domain", c.Domain, "serial_number", c.SerialNumber)
+		if err := h.Run(ctx, c); err != nil {
+			logger.Error("deploy hook failed", "hook", h.String(), "domain", c.Domain, "error", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
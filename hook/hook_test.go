@@ -0,0 +1,125 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestHook_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		hook  Hook
+		valid bool
+	}{
+		{"command", Hook{Command: "true"}, true},
+		{"signal", Hook{Signal: "SIGHUP", PIDFile: "/run/nginx.pid"}, true},
+		{"systemd", Hook{Reload: "nginx"}, true},
+		{"empty", Hook{}, false},
+		{"two kinds", Hook{Command: "true", Reload: "nginx"}, false},
+		{"signal without pid-file", Hook{Signal: "HUP"}, false},
+		{"pid-file without signal", Hook{PIDFile: "/run/nginx.pid"}, false},
+		{"unknown signal", Hook{Signal: "FOO", PIDFile: "/run/nginx.pid"}, false},
+		{"negative timeout", Hook{Command: "true", Timeout: -time.Second}, false},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			err := test.hook.Validate()
+			if test.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestHook_Run(t *testing.T) {
+	c := Context{
+		Name:         "web",
+		Domain:       "example.com",
+		Domains:      []string{"example.com", "www.example.com"},
+		SerialNumber: "01:02",
+		Output:       "/etc/ssl",
+		Dir:          "/etc/ssl/example.com",
+		Files:        []string{"/etc/ssl/example.com/cert.pem"},
+	}
+
+	t.Run("Test command environment", func(t *testing.T) {
+		out := filepath.Join(tempDir(t), "out")
+		h := Hook{Command: `echo "$GCLI_NAME $GCLI_DOMAIN $GCLI_DOMAINS $GCLI_SERIAL $GCLI_CERT $GCLI_FILES" > ` + out}
+
+		assert.Nil(t, h.Run(context.Background(), c))
+		data, err := ioutil.ReadFile(out)
+		assert.Nil(t, err)
+		assert.Equal(t, "web example.com example.com,www.example.com 01:02 /etc/ssl/example.com/cert.pem "+
+			"/etc/ssl/example.com/cert.pem\n", string(data))
+	})
+	t.Run("Test failing command", func(t *testing.T) {
+		err := Hook{Command: "echo broken config; exit 1"}.Run(context.Background(), c)
+
+		var e *failure.Error
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, failure.HookFailed, e.Kind)
+		assert.Contains(t, err.Error(), "broken config")
+		assert.Contains(t, err.Error(), "example.com")
+	})
+	t.Run("Test command timeout", func(t *testing.T) {
+		start := time.Now()
+		err := Hook{Command: "sleep 10 & wait", Timeout: 100 * time.Millisecond}.Run(context.Background(), c)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "timed out after 100ms")
+		assert.Equal(t, failure.HookFailed, failure.Classify(err).Kind)
+		assert.True(t, time.Since(start) < 5*time.Second)
+	})
+	t.Run("Test signal with missing pid-file", func(t *testing.T) {
+		h := Hook{Signal: "HUP", PIDFile: filepath.Join(tempDir(t), "missing.pid")}
+		err := h.Run(context.Background(), c)
+		assert.Equal(t, failure.HookFailed, failure.Classify(err).Kind)
+	})
+}
+
+func TestContext_Vars(t *testing.T) {
+	c := Context{Domain: "example.com", Dir: "/etc/ssl/example.com"}
+	vars := c.Vars()
+	assert.Equal(t, "pem", vars["GCLI_FORMAT"])
+	assert.Equal(t, "/etc/ssl/example.com/fullchain.pem", vars["GCLI_FULLCHAIN"])
+
+	c.Format = "p12"
+	vars = c.Vars()
+	assert.Equal(t, "p12", vars["GCLI_FORMAT"])
+	assert.Equal(t, "", vars["GCLI_CERT"])
+}
+
+func TestRunAll(t *testing.T) {
+	out := filepath.Join(tempDir(t), "out")
+	hooks := []Hook{
+		{Command: "exit 1"},
+		{Command: "echo ran >> " + out},
+	}
+
+	err := RunAll(context.Background(), hooks, Context{Domain: "example.com"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `"exit 1"`)
+
+	data, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, "ran\n", string(data))
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package hook
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHook_Run_Signal(t *testing.T) {
+	c := Context{Name: "web", Domain: "example.com"}
+
+	dir := tempDir(t)
+	pidFile := filepath.Join(dir, "web.pid")
+	if err := ioutil.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, syscall.SIGUSR1)
+	defer signal.Stop(received)
+
+	h := Hook{Signal: "usr1", PIDFile: filepath.Join(dir, "${GCLI_NAME}.pid")}
+	assert.Nil(t, h.Run(context.Background(), c))
+
+	select {
+	case sig := <-received:
+		assert.Equal(t, syscall.SIGUSR1, sig)
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal wasn't received")
+	}
+}
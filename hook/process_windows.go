@@ -0,0 +1,22 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// parseSignal returns an error as signals can't be sent to processes on Windows.
+func parseSignal(name string) (os.Signal, error) {
+	return nil, fmt.Errorf("signal hooks aren't supported on Windows")
+}
+
+// start starts the given command.
+func start(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+// kill stops the given command.
+func kill(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
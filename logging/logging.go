@@ -0,0 +1,175 @@
+// The logging package contains the leveled, structured logger used throughout gcli. Logs are written to stderr as
+// either text or JSON so they never mix with the results written to stdout. Values which may contain tokens or secrets
+// are redacted before they're written.
+package logging
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	Text = "text"
+	JSON = "json"
+)
+
+// Redacted replaces sensitive values in log messages.
+const Redacted = "<redacted>"
+
+// Levels contains the name of every supported log level from most to least verbose.
+var Levels = []string{"trace", "debug", "info", "warn", "error"}
+
+// sensitiveKeys are the parts of argument names whose values are always redacted.
+var sensitiveKeys = []string{"token", "secret", "password", "passphrase", "private_key", "plaintext"}
+
+// tokenPattern matches Vault tokens (i.e. s.xxx or hvs.xxx) and wrapping tokens appearing in values.
+var tokenPattern = regexp.MustCompile(`\b(?:s|b|r|hvs|hvb|hvr)\.[A-Za-z0-9_-]{20,}`)
+
+var (
+	mu            sync.RWMutex
+	defaultLogger hclog.Logger = hclog.NewNullLogger()
+)
+
+// L returns the default logger. It discards everything until SetDefault is called.
+func L() hclog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault sets the default logger returned by L.
+func SetDefault(l hclog.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLogger = l
+}
+
+// New returns a logger which writes logs at or above the given level (i.e. debug) to the given writer in the given
+// format (text or json). Sensitive arguments are redacted.
+func New(w io.Writer, level string, format string) (hclog.Logger, error) {
+	l := hclog.LevelFromString(level)
+	if l == hclog.NoLevel {
+		return nil, fmt.Errorf("unknown log level %q (expected one of %s)", level, strings.Join(Levels, ", "))
+	}
+
+	if format != Text && format != JSON {
+		return nil, fmt.Errorf("unknown log format %q (expected %s or %s)", format, Text, JSON)
+	}
+
+	return &redactingLogger{hclog.New(&hclog.LoggerOptions{
+		Name:       "gcli",
+		Level:      l,
+		Output:     w,
+		JSONFormat: format == JSON,
+	})}, nil
+}
+
+// Verbosity returns the log level for the given number of -v flags. Without any, only warnings and errors are logged.
+func Verbosity(count int) string {
+	switch {
+	case count <= 0:
+		return "warn"
+	case count == 1:
+		return "info"
+	case count == 2:
+		return "debug"
+	default:
+		return "trace"
+	}
+}
+
+// Redact returns a copy of the given key/value pairs with sensitive values replaced by Redacted. A value is sensitive
+// if its key contains a sensitive word (i.e. token or password), or if it contains something which looks like a Vault
+// token.
+func Redact(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	copy(redacted, args)
+
+	for i := 0; i+1 < len(redacted); i += 2 {
+		key, ok := redacted[i].(string)
+		if ok && SensitiveKey(key) {
+			redacted[i+1] = Redacted
+			continue
+		}
+		redacted[i+1] = RedactValue(redacted[i+1])
+	}
+
+	return redacted
+}
+
+// RedactValue replaces anything which looks like a Vault token in the given value with Redacted.
+func RedactValue(v interface{}) interface{} {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		return v
+	}
+
+	if tokenPattern.MatchString(s) {
+		return tokenPattern.ReplaceAllString(s, Redacted)
+	}
+	return v
+}
+
+// SensitiveKey returns true if values with the given key must be redacted.
+func SensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactingLogger wraps an hclog.Logger, redacting the arguments of every message. Loggers derived from it with With
+// or Named are also redacted.
+type redactingLogger struct {
+	hclog.Logger
+}
+
+func (l *redactingLogger) Log(level hclog.Level, msg string, args ...interface{}) {
+	l.Logger.Log(level, msg, Redact(args)...)
+}
+
+func (l *redactingLogger) Trace(msg string, args ...interface{}) {
+	l.Logger.Trace(msg, Redact(args)...)
+}
+
+func (l *redactingLogger) Debug(msg string, args ...interface{}) {
+	l.Logger.Debug(msg, Redact(args)...)
+}
+
+func (l *redactingLogger) Info(msg string, args ...interface{}) {
+	l.Logger.Info(msg, Redact(args)...)
+}
+
+func (l *redactingLogger) Warn(msg string, args ...interface{}) {
+	l.Logger.Warn(msg, Redact(args)...)
+}
+
+func (l *redactingLogger) Error(msg string, args ...interface{}) {
+	l.Logger.Error(msg, Redact(args)...)
+}
+
+func (l *redactingLogger) With(args ...interface{}) hclog.Logger {
+	return &redactingLogger{l.Logger.With(Redact(args)...)}
+}
+
+func (l *redactingLogger) Named(name string) hclog.Logger {
+	return &redactingLogger{l.Logger.Named(name)}
+}
+
+func (l *redactingLogger) ResetNamed(name string) hclog.Logger {
+	return &redactingLogger{l.Logger.ResetNamed(name)}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Test text format", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := New(&buf, "info", Text)
+		assert.Nil(t, err)
+
+		l.Debug("hidden")
+		l.Info("shown", "address", "https://vault.lab:8200")
+		assert.NotContains(t, buf.String(), "hidden")
+		assert.Contains(t, buf.String(), "[INFO]  gcli: shown: address=https://vault.lab:8200")
+	})
+	t.Run("Test JSON format", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := New(&buf, "debug", JSON)
+		assert.Nil(t, err)
+
+		l.Named("client").Debug("login", "path", "auth/userpass/login/test", "client_token", "s.abc")
+
+		var entry map[string]interface{}
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "debug", entry["@level"])
+		assert.Equal(t, "gcli.client", entry["@module"])
+		assert.Equal(t, "auth/userpass/login/test", entry["path"])
+		assert.Equal(t, Redacted, entry["client_token"])
+	})
+	t.Run("Test with invalid settings", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := New(&buf, "loud", Text)
+		assert.NotNil(t, err)
+		_, err = New(&buf, "info", "xml")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRedact(t *testing.T) {
+	token := "s.Q2Fz3mL8tW1vX9yZ0aBcDeFg"
+	args := []interface{}{
+		"password", "hunter2",
+		"vault_token", token,
+		"error", errors.New("permission denied for token " + token),
+		"address", "https://vault.lab:8200",
+		"attempt", 2,
+	}
+
+	redacted := Redact(args)
+	assert.Equal(t, []interface{}{
+		"password", Redacted,
+		"vault_token", Redacted,
+		"error", "permission denied for token " + Redacted,
+		"address", "https://vault.lab:8200",
+		"attempt", 2,
+	}, redacted)
+
+	// The original arguments are left untouched
+	assert.Equal(t, "hunter2", args[1])
+}
+
+func TestVerbosity(t *testing.T) {
+	assert.Equal(t, "warn", Verbosity(0))
+	assert.Equal(t, "info", Verbosity(1))
+	assert.Equal(t, "debug", Verbosity(2))
+	assert.Equal(t, "trace", Verbosity(5))
+}
@@ -0,0 +1,71 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/hook"
+	"path/filepath"
+)
+
+// Source reads the certificates written by the gcert service. It is satisfied by client.VaultClient.
+type Source interface {
+	GetCertificate(domain string) (*cert.Certificate, error)
+}
+
+// Deploy writes the certificates of the given domains read from the given Source to [Output]/[domain], running the
+// deploy hooks of the group for each certificate whose files changed. It returns the paths of the files which changed.
+// A failed hook doesn't stop the remaining certificates from being written; the error of the first one is returned as
+// a failure.Error of the HookFailed Kind. Nothing is done if the group has no output directory.
+func (g Group) Deploy(ctx context.Context, s Source, domains []string) ([]string, error) {
+	if g.Output == "" {
+		return []string{}, nil
+	}
+
+	changed := []string{}
+	var hookErr error
+	for _, domain := range domains {
+		certificate, err := s.GetCertificate(domain)
+		if err != nil {
+			return changed, fmt.Errorf("unable to read certificate for %s: %w", domain, err)
+		}
+
+		dir := g.CertificateDir(domain)
+		files, err := cert.Write(dir, certificate)
+		changed = append(changed, files...)
+		if err != nil {
+			return changed, fmt.Errorf("unable to write certificate for %s: %w", domain, err)
+		}
+
+		if len(files) == 0 {
+			continue
+		}
+		c := hook.Context{
+			Name:         g.Name,
+			Domain:       domain,
+			Domains:      g.Domains,
+			SerialNumber: certificate.SerialNumber,
+			Output:       g.Output,
+			Dir:          dir,
+			Files:        files,
+		}
+		if err := hook.RunAll(ctx, g.DeployHooks(), c); err != nil && hookErr == nil {
+			hookErr = err
+		}
+	}
+
+	return changed, hookErr
+}
+
+// CertificateDir returns the directory the certificate of the given domain is written to.
+func (g Group) CertificateDir(domain string) string {
+	return filepath.Join(g.Output, domain)
+}
+
+// DeployHooks returns the deploy hooks of the group, starting with the post-hook.
+func (g Group) DeployHooks() []hook.Hook {
+	if g.PostHook == "" {
+		return g.Hooks
+	}
+	return append([]hook.Hook{{Command: g.PostHook}}, g.Hooks...)
+}
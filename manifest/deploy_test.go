@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/hook"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeSource map[string]*cert.Certificate
+
+func (s fakeSource) GetCertificate(domain string) (*cert.Certificate, error) {
+	c, ok := s[domain]
+	if !ok {
+		return &cert.Certificate{}, errors.New("not found")
+	}
+	return c, nil
+}
+
+func TestGroup_Deploy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := fakeSource{"example.com": {Certificate: []byte("certificate"), PrivateKey: []byte("key")}}
+	marker := filepath.Join(dir, "hook")
+	g := Group{
+		Name:     "web",
+		Domains:  []string{"example.com"},
+		Output:   filepath.Join(dir, "certs"),
+		PostHook: `echo "$GCLI_NAME $GCLI_DOMAINS" >> ` + marker,
+	}
+
+	changed, err := g.Deploy(context.Background(), source, []string{"example.com"})
+	assert.Nil(t, err)
+	assert.Len(t, changed, 3)
+	data, err := ioutil.ReadFile(filepath.Join(g.CertificateDir("example.com"), cert.CertificateFile))
+	assert.Nil(t, err)
+	assert.Equal(t, "certificate", string(data))
+
+	t.Run("Test post-hook only runs when files change", func(t *testing.T) {
+		changed, err := g.Deploy(context.Background(), source, []string{"example.com"})
+		assert.Nil(t, err)
+		assert.Empty(t, changed)
+
+		data, err := ioutil.ReadFile(marker)
+		assert.Nil(t, err)
+		assert.Equal(t, "web example.com\n", string(data))
+	})
+	t.Run("Test hooks are given the certificate", func(t *testing.T) {
+		source["example.com"] = &cert.Certificate{Domain: "example.com", SerialNumber: "01:02",
+			Certificate: []byte("renewed"), PrivateKey: []byte("key")}
+		g := g
+		g.PostHook = ""
+		g.Hooks = []hook.Hook{{Command: `echo "$GCLI_DOMAIN $GCLI_SERIAL $GCLI_CERT" > ` + marker}}
+
+		_, err := g.Deploy(context.Background(), source, []string{"example.com"})
+		assert.Nil(t, err)
+
+		data, err := ioutil.ReadFile(marker)
+		assert.Nil(t, err)
+		assert.Equal(t, "example.com 01:02 "+filepath.Join(g.CertificateDir("example.com"), cert.CertificateFile)+"\n",
+			string(data))
+	})
+	t.Run("Test failing hook", func(t *testing.T) {
+		source["example.com"] = &cert.Certificate{Certificate: []byte("renewed again"), PrivateKey: []byte("key")}
+		source["www.example.com"] = &cert.Certificate{Certificate: []byte("www"), PrivateKey: []byte("key")}
+		g := g
+		g.PostHook = "echo failed; exit 1"
+
+		changed, err := g.Deploy(context.Background(), source, []string{"example.com", "www.example.com"})
+		assert.Equal(t, failure.HookFailed, failure.Classify(err).Kind)
+		assert.Contains(t, err.Error(), "failed")
+
+		// The remaining certificates are still written
+		assert.Contains(t, changed, filepath.Join(g.CertificateDir("www.example.com"), cert.CertificateFile))
+	})
+	t.Run("Test missing certificate", func(t *testing.T) {
+		_, err := g.Deploy(context.Background(), source, []string{"missing.com"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Test without output", func(t *testing.T) {
+		changed, err := Group{Name: "web"}.Deploy(context.Background(), nil, []string{"example.com"})
+		assert.Nil(t, err)
+		assert.Empty(t, changed)
+	})
+}
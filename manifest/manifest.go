@@ -0,0 +1,145 @@
+// The manifest package contains the manifest format used to request many certificates from the gcert service at once,
+// along with a bounded worker pool for processing the certificate groups it lists.
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmgilman/gcli/hook"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+// The Let's Encrypt endpoints a certificate can be requested from.
+const (
+	Staging    = "staging"
+	Production = "production"
+)
+
+// DefaultWorkers is the number of groups processed concurrently when no worker count is given.
+const DefaultWorkers = 4
+
+// Manifest lists groups of certificates to request from the gcert service.
+type Manifest struct {
+	Workers      int     `yaml:"workers"`
+	Certificates []Group `yaml:"certificates"`
+}
+
+// Group is a single certificate request. The first domain is the primary domain of the certificate and the rest are
+// added as SANs. If Output is set, the issued certificates are written to [Output]/[domain], and the deploy hooks are run
+// for each certificate whose files changed. Certificates are always written as PEM files (see cert.Write), which is what
+// the watcher checks on disk. PostHook is shorthand for a command hook run before the others.
+type Group struct {
+	Name     string      `yaml:"name"`
+	Domains  []string    `yaml:"domains"`
+	Endpoint string      `yaml:"endpoint"`
+	Output   string      `yaml:"output"`
+	PostHook string      `yaml:"post-hook"`
+	Hooks    []hook.Hook `yaml:"hooks"`
+}
+
+// Load reads and validates the manifest at the given path. Groups without a name are named after their primary domain
+// and default to the staging endpoint. Relative output directories are resolved relative to the manifest.
+func Load(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &Manifest{}, err
+	}
+
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return &Manifest{}, fmt.Errorf("unable to parse manifest %s: %w", path, err)
+	}
+
+	for i := range m.Certificates {
+		g := &m.Certificates[i]
+		if g.Name == "" && len(g.Domains) > 0 {
+			g.Name = g.Domains[0]
+		}
+		if g.Endpoint == "" {
+			g.Endpoint = Staging
+		}
+		if g.Output != "" && !filepath.IsAbs(g.Output) {
+			g.Output = filepath.Join(filepath.Dir(path), g.Output)
+		}
+	}
+
+	if err := m.Validate(); err != nil {
+		return &Manifest{}, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// Validate returns an error describing the first problem found with the manifest.
+func (m *Manifest) Validate() error {
+	if len(m.Certificates) == 0 {
+		return fmt.Errorf("no certificates are listed")
+	}
+	if m.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
+
+	names := map[string]bool{}
+	for i, g := range m.Certificates {
+		if len(g.Domains) == 0 {
+			return fmt.Errorf("certificate %d has no domains", i+1)
+		}
+		if names[g.Name] {
+			return fmt.Errorf("certificate %s is listed more than once", g.Name)
+		}
+		names[g.Name] = true
+
+		if g.Endpoint != Staging && g.Endpoint != Production {
+			return fmt.Errorf("certificate %s has unknown endpoint %q (expected %s or %s)", g.Name, g.Endpoint,
+				Staging, Production)
+		}
+		if (g.PostHook != "" || len(g.Hooks) > 0) && g.Output == "" {
+			return fmt.Errorf("certificate %s has hooks but no output directory", g.Name)
+		}
+		for j, h := range g.Hooks {
+			if err := h.Validate(); err != nil {
+				return fmt.Errorf("certificate %s hook %d is invalid: %w", g.Name, j+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Run calls the given function with the index of each of the given groups and the group itself, processing at most the
+// given number of groups concurrently. It returns the error returned for each group in the same order as the groups.
+// Groups which haven't started when the context is done fail with the context's error.
+func Run(ctx context.Context, groups []Group, workers int, f func(context.Context, int, Group) error) []error {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	errs := make([]error, len(groups))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(groups); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = f(ctx, i, groups[i])
+			}
+		}()
+	}
+
+	for i := range groups {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
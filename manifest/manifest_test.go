@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"github.com/jmgilman/gcli/hook"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeManifest(t *testing.T, text string) string {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "certs.yaml")
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeManifest(t, `
+workers: 2
+certificates:
+  - domains: [example.com, www.example.com]
+    output: certs
+    post-hook: systemctl reload nginx
+    hooks:
+      - signal: HUP
+        pid-file: /run/haproxy.pid
+        timeout: 5s
+  - name: api
+    domains: [api.example.com]
+    endpoint: production
+`)
+
+	m, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, m.Workers)
+	assert.Len(t, m.Certificates, 2)
+
+	assert.Equal(t, "example.com", m.Certificates[0].Name)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, m.Certificates[0].Domains)
+	assert.Equal(t, Staging, m.Certificates[0].Endpoint)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "certs"), m.Certificates[0].Output)
+	assert.Equal(t, "systemctl reload nginx", m.Certificates[0].PostHook)
+	assert.Equal(t, []hook.Hook{
+		{Command: "systemctl reload nginx"},
+		{Signal: "HUP", PIDFile: "/run/haproxy.pid", Timeout: 5 * time.Second},
+	}, m.Certificates[0].DeployHooks())
+
+	assert.Equal(t, "api", m.Certificates[1].Name)
+	assert.Equal(t, Production, m.Certificates[1].Endpoint)
+	assert.Equal(t, "", m.Certificates[1].Output)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"no certificates", "certificates: []"},
+		{"no domains", "certificates: [{name: web}]"},
+		{"duplicate names", "certificates: [{domains: [a.com]}, {domains: [a.com]}]"},
+		{"unknown endpoint", "certificates: [{domains: [a.com], endpoint: prod}]"},
+		{"post-hook without output", "certificates: [{domains: [a.com], post-hook: 'true'}]"},
+		{"hooks without output", "certificates: [{domains: [a.com], hooks: [{reload: nginx}]}]"},
+		{"invalid hook", "certificates: [{domains: [a.com], output: certs, hooks: [{signal: HUP}]}]"},
+		{"unknown field", "certificates: [{domains: [a.com], sans: [b.com]}]"},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			_, err := Load(writeManifest(t, test.text))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	groups := []Group{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+
+	t.Run("Test concurrency is bounded", func(t *testing.T) {
+		var running, max int32
+		errs := Run(context.Background(), groups, 2, func(ctx context.Context, i int, g Group) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+
+			if g.Name == "c" && i == 2 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+
+		assert.Len(t, errs, 5)
+		assert.Nil(t, errs[0])
+		assert.NotNil(t, errs[2])
+		assert.Nil(t, errs[4])
+		assert.Equal(t, int32(2), max)
+	})
+	t.Run("Test cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		errs := Run(ctx, groups, 0, func(ctx context.Context, i int, g Group) error {
+			return nil
+		})
+		for _, err := range errs {
+			assert.Equal(t, context.Canceled, err)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+// The output package renders the results of gcli commands in the format selected with --output. Results are rendered
+// as JSON or YAML for scripts (i.e. jq or Ansible), or as an aligned table for people.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	JSON  = "json"
+	YAML  = "yaml"
+	Table = "table"
+)
+
+// Formats contains every supported output format.
+var Formats = []string{Table, JSON, YAML}
+
+// Tabular is implemented by results which can be rendered as a table. Rows returns the column headers and the rows of
+// the table. If the headers are empty, only the rows are rendered.
+type Tabular interface {
+	Rows() ([]string, [][]string)
+}
+
+// KeyValues is a generic result of named values (i.e. the data of a secret). As a table, it's rendered with a row for
+// each key in sorted order.
+type KeyValues map[string]interface{}
+
+func (kv KeyValues) Rows() ([]string, [][]string) {
+	keys := make([]string, 0, len(kv))
+	for key := range kv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, len(keys))
+	for i, key := range keys {
+		rows[i] = []string{key, Value(kv[key])}
+	}
+	return []string{"KEY", "VALUE"}, rows
+}
+
+// Write renders the given result to the given writer in the given format. JSON and YAML are rendered from the JSON
+// encoding of the result, so results only need json struct tags. Results rendered as a table must be Tabular.
+func Write(w io.Writer, format string, result interface{}) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case YAML:
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		ordered, err := decodeOrdered(decoder)
+		if err != nil {
+			return err
+		}
+
+		data, err = yaml.Marshal(ordered)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case Table, "":
+		table, ok := result.(Tabular)
+		if !ok {
+			return fmt.Errorf("results of type %T can't be rendered as a table", result)
+		}
+		return writeTable(w, table)
+	default:
+		return fmt.Errorf("unknown output format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Value returns the given value formatted for a table cell. Lists are joined with commas and maps are rendered as
+// JSON.
+func Value(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, value := range v {
+			values[i] = Value(value)
+		}
+		return strings.Join(values, ", ")
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// decodeOrdered decodes the next JSON value from the given decoder, decoding objects as a yaml.MapSlice so the fields of
+// structs are rendered in the order they were declared rather than sorted.
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			list := []interface{}{}
+			for decoder.More() {
+				value, err := decodeOrdered(decoder)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			_, err := decoder.Token()
+			return list, err
+		}
+
+		object := yaml.MapSlice{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Number:
+		if i, err := token.Int64(); err == nil {
+			return i, nil
+		}
+		return token.Float64()
+	default:
+		return token, nil
+	}
+}
+
+func writeTable(w io.Writer, t Tabular) error {
+	headers, rows := t.Rows()
+
+	// Single column tables don't need aligning, and writing them directly keeps values containing tabs intact
+	if len(headers) <= 1 && singleColumn(rows) {
+		for _, row := range append([][]string{headers}, rows...) {
+			if len(row) == 0 {
+				continue
+			}
+			if _, err := fmt.Fprintln(w, row[0]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(headers) > 0 {
+		if _, err := fmt.Fprintln(tw, strings.Join(headers, "\t")); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+func singleColumn(rows [][]string) bool {
+	for _, row := range rows {
+		if len(row) > 1 {
+			return false
+		}
+	}
+	return true
+}
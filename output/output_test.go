@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testResult struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains"`
+	Count   int      `json:"count"`
+}
+
+func (r testResult) Rows() ([]string, [][]string) {
+	return []string{"NAME", "DOMAINS"}, [][]string{{r.Name, Value(r.Domains)}}
+}
+
+func TestWrite(t *testing.T) {
+	result := testResult{Name: "test", Domains: []string{"a.com", "b.com"}, Count: 2}
+
+	t.Run("Test JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Write(&buf, JSON, result)
+		assert.Nil(t, err)
+		assert.Equal(t, "{\n  \"name\": \"test\",\n  \"domains\": [\n    \"a.com\",\n    \"b.com\"\n  ],\n  \"count\": 2\n}\n",
+			buf.String())
+	})
+	t.Run("Test YAML", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Write(&buf, YAML, result)
+		assert.Nil(t, err)
+		assert.Equal(t, "name: test\ndomains:\n- a.com\n- b.com\ncount: 2\n", buf.String())
+	})
+	t.Run("Test YAML with a list", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Write(&buf, YAML, []testResult{result})
+		assert.Nil(t, err)
+		assert.Equal(t, "- name: test\n  domains:\n  - a.com\n  - b.com\n  count: 2\n", buf.String())
+	})
+	t.Run("Test table", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Write(&buf, Table, result)
+		assert.Nil(t, err)
+		assert.Equal(t, "NAME  DOMAINS\ntest  a.com, b.com\n", buf.String())
+	})
+	t.Run("Test table with a result that isn't tabular", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Write(&buf, Table, []string{"test"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Test unknown format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Write(&buf, "xml", result)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestKeyValues(t *testing.T) {
+	var buf bytes.Buffer
+	kv := KeyValues{
+		"password": "secret",
+		"nested":   map[string]interface{}{"a": 1},
+		"hosts":    []interface{}{"a", "b"},
+	}
+
+	err := Write(&buf, Table, kv)
+	assert.Nil(t, err)
+	assert.Equal(t, "KEY       VALUE\nhosts     a, b\nnested    {\"a\":1}\npassword  secret\n", buf.String())
+}
+
+type valueResult string
+
+func (r valueResult) Rows() ([]string, [][]string) {
+	return []string{}, [][]string{{string(r)}}
+}
+
+func TestWrite_SingleColumn(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, Table, valueResult("a\tb"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a\tb\n", buf.String())
+}
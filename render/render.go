@@ -0,0 +1,122 @@
+// The render package contains functions for rendering Go templates which reference secrets, certificates, and SSH CA
+// keys stored in Vault and writing the results to the local filesystem.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/files"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+)
+
+//go:generate moq -out ../internal/mocks/sourceinterface.go -pkg mocks . Source
+
+// Source represents the backend used for resolving the template functions. It is satisfied by client.VaultClient.
+type Source interface {
+	ReadSecret(path string) (map[string]interface{}, error)
+	GetCertificate(domain string) (*cert.Certificate, error)
+	SSHCAPublicKey(mount string) (string, error)
+}
+
+// Certificate is the value returned by the cert template function. The fields are PEM encoded strings so they can be
+// used directly in a template (i.e. {{ (cert "example.com").Certificate }}).
+type Certificate struct {
+	Certificate string
+	PrivateKey  string
+	Issuer      string
+	Chain       string
+}
+
+// Template represents a template file which is rendered to a destination file.
+type Template struct {
+	Source      string
+	Destination string
+	Mode        os.FileMode
+	OnChange    string
+}
+
+// FuncMap returns the functions made available to templates, backed by the given Source:
+//
+//	secret "path" "field" - returns the given field of the secret at the given path
+//	cert "domain"         - returns the Certificate written by gcert for the given domain
+//	sshCA "mount"         - returns the public key of the SSH CA at the given mount
+func FuncMap(s Source) template.FuncMap {
+	return template.FuncMap{
+		"secret": func(path string, field string) (string, error) {
+			data, err := s.ReadSecret(path)
+			if err != nil {
+				return "", err
+			}
+
+			value, ok := data[field]
+			if !ok {
+				return "", fmt.Errorf("secret at %s has no field named %s", path, field)
+			}
+
+			return fmt.Sprint(value), nil
+		},
+		"cert": func(domain string) (*Certificate, error) {
+			c, err := s.GetCertificate(domain)
+			if err != nil {
+				return &Certificate{}, err
+			}
+
+			return &Certificate{
+				Certificate: string(c.Certificate),
+				PrivateKey:  string(c.PrivateKey),
+				Issuer:      string(c.IssuerCertificate),
+				Chain:       string(c.Certificate) + string(c.IssuerCertificate),
+			}, nil
+		},
+		"sshCA": s.SSHCAPublicKey,
+	}
+}
+
+// Render parses the given template text and executes it with the functions returned by FuncMap.
+func Render(s Source, name string, text string) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(FuncMap(s)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return []byte{}, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Execute renders the template and atomically writes the result to the destination file. If the contents of the
+// destination changed and an OnChange command is configured, the command is run using the system shell. It returns
+// whether the destination was changed.
+func (t *Template) Execute(s Source) (bool, error) {
+	text, err := ioutil.ReadFile(t.Source)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := Render(s, filepath.Base(t.Source), string(text))
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := files.WriteAtomic(t.Destination, result, t.Mode)
+	if err != nil {
+		return false, err
+	}
+
+	if changed && t.OnChange != "" {
+		output, err := exec.Command("sh", "-c", t.OnChange).CombinedOutput()
+		if err != nil {
+			return changed, fmt.Errorf("on-change command failed: %w: %s", err, output)
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,121 @@
+package render_test
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/internal/mocks"
+	"github.com/jmgilman/gcli/render"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newMockSource() *mocks.SourceMock {
+	return &mocks.SourceMock{
+		ReadSecretFunc: func(path string) (map[string]interface{}, error) {
+			if path != "secret/test" {
+				return map[string]interface{}{}, fmt.Errorf("no secret was found at %s", path)
+			}
+			return map[string]interface{}{"password": "hunter2"}, nil
+		},
+		GetCertificateFunc: func(domain string) (*cert.Certificate, error) {
+			return &cert.Certificate{
+				Domain:            domain,
+				Certificate:       []byte("cert\n"),
+				PrivateKey:        []byte("key\n"),
+				IssuerCertificate: []byte("issuer\n"),
+			}, nil
+		},
+		SSHCAPublicKeyFunc: func(mount string) (string, error) {
+			return "ssh-rsa " + mount, nil
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	source := newMockSource()
+
+	t.Run("Test secret function", func(t *testing.T) {
+		result, err := render.Render(source, "test", `pass={{ secret "secret/test" "password" }}`)
+		assert.Nil(t, err)
+		assert.Equal(t, "pass=hunter2", string(result))
+	})
+
+	t.Run("Test secret function with missing field", func(t *testing.T) {
+		_, err := render.Render(source, "test", `{{ secret "secret/test" "missing" }}`)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test secret function with missing secret", func(t *testing.T) {
+		_, err := render.Render(source, "test", `{{ secret "secret/missing" "password" }}`)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test cert function", func(t *testing.T) {
+		result, err := render.Render(source, "test", `{{ with cert "example.com" }}{{ .Chain }}{{ .PrivateKey }}{{ end }}`)
+		assert.Nil(t, err)
+		assert.Equal(t, "cert\nissuer\nkey\n", string(result))
+	})
+
+	t.Run("Test sshCA function", func(t *testing.T) {
+		result, err := render.Render(source, "test", `{{ sshCA "ssh" }}`)
+		assert.Nil(t, err)
+		assert.Equal(t, "ssh-rsa ssh", string(result))
+	})
+}
+
+func TestTemplate_Execute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "test.tmpl")
+	if err := ioutil.WriteFile(source, []byte(`{{ secret "secret/test" "password" }}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marker := filepath.Join(dir, "changed")
+	tmpl := &render.Template{
+		Source:      source,
+		Destination: filepath.Join(dir, "test.conf"),
+		Mode:        0600,
+		OnChange:    "printf x >> " + marker,
+	}
+
+	t.Run("Test first render runs on-change command", func(t *testing.T) {
+		changed, err := tmpl.Execute(newMockSource())
+		assert.Nil(t, err)
+		assert.True(t, changed)
+
+		contents, err := ioutil.ReadFile(tmpl.Destination)
+		assert.Nil(t, err)
+		assert.Equal(t, "hunter2", string(contents))
+
+		calls, err := ioutil.ReadFile(marker)
+		assert.Nil(t, err)
+		assert.Equal(t, "x", string(calls))
+	})
+
+	t.Run("Test unchanged render skips on-change command", func(t *testing.T) {
+		changed, err := tmpl.Execute(newMockSource())
+		assert.Nil(t, err)
+		assert.False(t, changed)
+
+		calls, err := ioutil.ReadFile(marker)
+		assert.Nil(t, err)
+		assert.Equal(t, "x", string(calls))
+	})
+
+	t.Run("Test failing on-change command", func(t *testing.T) {
+		failing := *tmpl
+		failing.Destination = filepath.Join(dir, "other.conf")
+		failing.OnChange = "exit 1"
+		changed, err := failing.Execute(newMockSource())
+		assert.True(t, changed)
+		assert.NotNil(t, err)
+	})
+}
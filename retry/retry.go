@@ -0,0 +1,153 @@
+// The retry package contains the retry policy shared by the calls gcli makes to Vault and the gcert service. Calls are
+// retried with an exponential backoff when they fail with a transient error (i.e. a standby node or a server which is
+// restarting).
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/vault/api"
+	"github.com/jmgilman/gcli/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Policy determines how many times a call is attempted and how long to wait between attempts. The wait before each
+// retry doubles from MinBackoff up to MaxBackoff, with a random jitter applied so many clients don't retry in lockstep.
+// Each retry is logged at the debug level.
+type Policy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultPolicy returns the Policy used when no retry settings are configured.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+	}
+}
+
+// Backoff returns how long to wait before retrying after the given number of failed attempts (starting at 1). The
+// wait is chosen randomly between half and all of the exponential backoff for the attempt.
+func (p *Policy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	backoff := p.MinBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// Do calls the given function until it succeeds, it returns an error which isn't Retryable, or the maximum number of
+// attempts is reached. The last error is returned. Waiting between attempts stops early if the given context is done,
+// in which case the context's error is returned. The given name identifies the call in log messages. Only idempotent
+// calls should be made with Do; use DoWith to decide which errors of other calls are safe to retry.
+func (p *Policy) Do(ctx context.Context, name string, f func() error) error {
+	return p.DoWith(ctx, name, Retryable, f)
+}
+
+// DoWith is the same as Do, except errors are only retried if the given function returns true for them.
+func (p *Policy) DoWith(ctx context.Context, name string, retryable func(error) bool, f func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = f()
+		if err == nil || !retryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		wait := p.Backoff(attempt)
+		logging.L().Named("retry").Debug("call failed, retrying", "call", name, "wait", wait, "attempt", attempt+1,
+			"max_attempts", p.MaxAttempts, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Retryable returns true if the given error is transient, meaning the call which returned it may succeed if it's
+// retried. This is the case when the server couldn't be connected to, when a Vault server responds that it's a standby
+// or otherwise unavailable (i.e. 503), or when a gRPC server returns Unavailable. Errors from a cancelled or expired
+// context are never retryable.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		return RetryableStatus(respErr.StatusCode)
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.Unavailable
+	}
+
+	var opErr *net.OpError
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		(errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// NotSent returns true if the given error occurred before a request could be sent, i.e. while connecting to the server.
+// Calls which aren't idempotent (i.e. ordering a certificate) may only be retried after errors like these, since the
+// server may have acted on a request which failed any other way (i.e. a connection reset while waiting on a response).
+func NotSent(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var opErr *net.OpError
+	return errors.Is(err, syscall.ECONNREFUSED) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// Idempotent returns true if an HTTP request with the given method can be repeated without changing its outcome. Vault
+// writes (i.e. issuing a certificate) are made with PUT or POST, which aren't.
+func Idempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPut, http.MethodPost, http.MethodPatch:
+		return false
+	default:
+		return true
+	}
+}
+
+// UnprocessedStatus returns true if a response with the given HTTP status code means the server refused the request
+// without acting on it (i.e. it's sealed, a standby, or rate limited), so it's safe to retry even if it isn't
+// idempotent.
+func UnprocessedStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// RetryableStatus returns true if a response with the given HTTP status code is transient.
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := &Policy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{4, 2500 * time.Millisecond, 5 * time.Second},
+		{50, 2500 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Test attempt %d", test.attempt), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				wait := p.Backoff(test.attempt)
+				assert.True(t, wait >= test.min && wait <= test.max, "wait of %s is out of range", wait)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	p := &Policy{MaxAttempts: 3}
+
+	t.Run("Test with a transient error", func(t *testing.T) {
+		calls := 0
+		err := p.Do(context.Background(), "test", func() error {
+			calls++
+			if calls < 3 {
+				return unavailable
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("Test with too many transient errors", func(t *testing.T) {
+		calls := 0
+		err := p.Do(context.Background(), "test", func() error {
+			calls++
+			return unavailable
+		})
+		assert.Equal(t, unavailable, err)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("Test with an error that isn't transient", func(t *testing.T) {
+		calls := 0
+		err := p.Do(context.Background(), "test", func() error {
+			calls++
+			return status.Error(codes.InvalidArgument, "invalid")
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("Test with a cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := &Policy{MaxAttempts: 3, MinBackoff: time.Hour, MaxBackoff: time.Hour}
+
+		calls := 0
+		err := slow.Do(ctx, "test", func() error {
+			calls++
+			cancel()
+			return unavailable
+		})
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	refused := &net.OpError{Op: "read", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"standby", &api.ResponseError{StatusCode: 503}, true},
+		{"permission denied", &api.ResponseError{StatusCode: 403}, false},
+		{"gRPC unavailable", status.Error(codes.Unavailable, "unavailable"), true},
+		{"gRPC not found", status.Error(codes.NotFound, "not found"), false},
+		{"connection refused", fmt.Errorf("request failed: %w", refused), true},
+		{"dial", &net.OpError{Op: "dial", Err: errors.New("no route to host")}, true},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("failed"), false},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			assert.Equal(t, test.retryable, Retryable(test.err))
+		})
+	}
+}
+
+func TestPolicy_DoWith(t *testing.T) {
+	p := &Policy{MaxAttempts: 3}
+	reset := &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	calls := 0
+	err := p.DoWith(context.Background(), "test", NotSent, func() error {
+		calls++
+		return reset
+	})
+	assert.Equal(t, reset, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestNotSent(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		notSent bool
+	}{
+		{"dial", &net.OpError{Op: "dial", Err: errors.New("no route to host")}, true},
+		{"connection refused", os.NewSyscallError("connect", syscall.ECONNREFUSED), true},
+		{"connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, false},
+		{"gRPC unavailable", status.Error(codes.Unavailable, "transport is closing"), false},
+		{"standby", &api.ResponseError{StatusCode: 503}, false},
+		{"cancelled", context.Canceled, false},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			assert.Equal(t, test.notSent, NotSent(test.err))
+		})
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	assert.True(t, Idempotent("GET"))
+	assert.True(t, Idempotent("LIST"))
+	assert.True(t, Idempotent("Delete"))
+	assert.False(t, Idempotent("PUT"))
+	assert.False(t, Idempotent("Post"))
+}
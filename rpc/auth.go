@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmgilman/gcli/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// The methods of authenticating calls to the gcert service.
+const (
+	// AuthNone sends calls anonymously.
+	AuthNone = "none"
+
+	// AuthToken sends the caller's Vault token.
+	AuthToken = "token"
+
+	// AuthJWT sends an identity token (a JWT) signed by Vault's identity secrets engine.
+	AuthJWT = "jwt"
+)
+
+// AuthMethods contains every supported authentication method.
+var AuthMethods = []string{AuthNone, AuthToken, AuthJWT}
+
+// The metadata keys credentials are sent with.
+const (
+	TokenKey         = "x-vault-token"
+	AuthorizationKey = "authorization"
+)
+
+// Credential returns the credential attached to a call. It's called once for every call, so short-lived credentials
+// (i.e. JWTs) are never sent after they expire.
+type Credential func(ctx context.Context) (string, error)
+
+// AuthInterceptor returns a gRPC interceptor which attaches the credential returned by the given function to every call
+// as metadata. The metadata key depends on the given method: the Vault token is sent as x-vault-token and a JWT as a
+// bearer token in the authorization key. A call fails without being sent if
+// the credential can't be retrieved.
+func AuthInterceptor(method string, credential Credential) (grpc.UnaryClientInterceptor, error) {
+	var key, prefix string
+	switch method {
+	case AuthToken:
+		key = TokenKey
+	case AuthJWT:
+		key, prefix = AuthorizationKey, "Bearer "
+	default:
+		return nil, fmt.Errorf("unknown authentication method %q (expected one of %s)", method,
+			strings.Join(AuthMethods[1:], ", "))
+	}
+
+	return func(ctx context.Context, rpcMethod string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		value, err := credential(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get credentials for %s: %w", rpcMethod, err)
+		}
+
+		logging.L().Named("rpc").Trace("attaching credentials", "method", rpcMethod, "auth", method)
+		ctx = metadata.AppendToOutgoingContext(ctx, key, prefix+value)
+		return invoker(ctx, rpcMethod, req, reply, cc, opts...)
+	}, nil
+}
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	tests := []struct {
+		method string
+		key    string
+		value  string
+	}{
+		{AuthToken, TokenKey, "s.token"},
+		{AuthJWT, AuthorizationKey, "Bearer s.token"},
+	}
+
+	for _, test := range tests {
+		t.Run("Test "+test.method, func(t *testing.T) {
+			calls := 0
+			interceptor, err := AuthInterceptor(test.method, func(ctx context.Context) (string, error) {
+				calls++
+				return "s.token", nil
+			})
+			assert.Nil(t, err)
+
+			var md metadata.MD
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+				opts ...grpc.CallOption) error {
+				md, _ = metadata.FromOutgoingContext(ctx)
+				return nil
+			}
+
+			for i := 0; i < 2; i++ {
+				err = interceptor(context.Background(), "/test", nil, nil, nil, invoker)
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, []string{test.value}, md.Get(test.key))
+			assert.Equal(t, 2, calls)
+		})
+	}
+
+	t.Run("Test credential error", func(t *testing.T) {
+		interceptor, err := AuthInterceptor(AuthToken, func(ctx context.Context) (string, error) {
+			return "", errors.New("not logged in")
+		})
+		assert.Nil(t, err)
+
+		invoked := false
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+			opts ...grpc.CallOption) error {
+			invoked = true
+			return nil
+		}
+
+		err = interceptor(context.Background(), "/test", nil, nil, nil, invoker)
+		assert.NotNil(t, err)
+		assert.False(t, invoked)
+	})
+	t.Run("Test unknown method", func(t *testing.T) {
+		_, err := AuthInterceptor("password", nil)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmgilman/gcli/logging"
+	"google.golang.org/grpc"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SRVService is the service name of the DNS SRV records gcert servers are published with (_gcert._tcp.<domain>).
+const SRVService = "gcert"
+
+// Resolver looks up DNS SRV records. It's implemented by *net.Resolver.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// LookupServers returns the addresses (host:port) of the gcert servers published in the _gcert._tcp SRV records of the
+// given domain. Addresses are ordered by priority, with servers of the same priority ordered randomly by weight.
+func LookupServers(ctx context.Context, r Resolver, domain string) ([]string, error) {
+	_, records, err := r.LookupSRV(ctx, SRVService, "tcp", domain)
+	if err != nil {
+		return []string{}, fmt.Errorf("unable to look up gcert servers for %s: %w", domain, err)
+	}
+
+	var servers []string
+	for _, record := range records {
+		// A target of "." means the service is explicitly not available
+		target := strings.TrimSuffix(record.Target, ".")
+		if target == "" {
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+	}
+
+	if len(servers) == 0 {
+		return []string{}, fmt.Errorf("no gcert servers are published for %s", domain)
+	}
+
+	logging.L().Named("rpc").Debug("discovered gcert servers", "domain", domain, "servers", servers)
+	return servers, nil
+}
+
+// DialAny dials each of the given servers in order, returning a connection to the first one which succeeds along with
+// its address. Since a non-blocking dial always succeeds, falling back only happens when dialing with WithBlock (or an
+// option implying it).
+func DialAny(ctx context.Context, servers []string, opts ...Option) (*grpc.ClientConn, string, error) {
+	if len(servers) == 0 {
+		return &grpc.ClientConn{}, "", errors.New("no gcert servers were given")
+	}
+
+	logger := logging.L().Named("rpc")
+	var lastErr error
+	for _, server := range servers {
+		conn, err := DialContext(ctx, server, opts...)
+		if err == nil {
+			return conn, server, nil
+		}
+		if ctx.Err() != nil {
+			return &grpc.ClientConn{}, "", err
+		}
+
+		logger.Warn("unable to connect to gcert server", "server", server, "error", err)
+		lastErr = err
+	}
+
+	if len(servers) == 1 {
+		return &grpc.ClientConn{}, "", lastErr
+	}
+	return &grpc.ClientConn{}, "", fmt.Errorf("no gcert server could be reached (tried %s): %w",
+		strings.Join(servers, ", "), lastErr)
+}
@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeResolver stands in for a DNS server, returning fixed SRV records for each name.
+type fakeResolver map[string][]*net.SRV
+
+func (r fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	records, ok := r["_"+service+"._"+proto+"."+name]
+	if !ok {
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return name, records, nil
+}
+
+func TestLookupServers(t *testing.T) {
+	resolver := fakeResolver{
+		"_gcert._tcp.lab.example": {
+			{Target: "gcert1.lab.example.", Port: 8080, Priority: 10},
+			{Target: "gcert2.lab.example.", Port: 8081, Priority: 20},
+		},
+		"_gcert._tcp.none.example": {
+			{Target: ".", Port: 0},
+		},
+	}
+
+	t.Run("Test lookup", func(t *testing.T) {
+		servers, err := LookupServers(context.Background(), resolver, "lab.example")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"gcert1.lab.example:8080", "gcert2.lab.example:8081"}, servers)
+	})
+	t.Run("Test service not available", func(t *testing.T) {
+		_, err := LookupServers(context.Background(), resolver, "none.example")
+		assert.NotNil(t, err)
+	})
+	t.Run("Test missing records", func(t *testing.T) {
+		_, err := LookupServers(context.Background(), resolver, "missing.example")
+		var dnsErr *net.DNSError
+		assert.True(t, errors.As(err, &dnsErr))
+	})
+}
+
+func TestDialAny(t *testing.T) {
+	addr, _ := newHealthServer(t, true)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := listener.Addr().String()
+	listener.Close()
+
+	t.Run("Test falling back", func(t *testing.T) {
+		conn, server, err := DialAny(context.Background(), []string{down, addr}, WithInsecure(),
+			WithTimeout(200*time.Millisecond))
+		assert.Nil(t, err)
+		assert.Equal(t, addr, server)
+		conn.Close()
+	})
+	t.Run("Test every server failing", func(t *testing.T) {
+		_, _, err := DialAny(context.Background(), []string{down, down}, WithInsecure(),
+			WithTimeout(200*time.Millisecond))
+		assert.NotNil(t, err)
+		assert.True(t, errors.Is(err, syscall.ECONNREFUSED))
+	})
+	t.Run("Test no servers", func(t *testing.T) {
+		_, _, err := DialAny(context.Background(), []string{})
+		assert.NotNil(t, err)
+	})
+}
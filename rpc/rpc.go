@@ -1,18 +1,258 @@
+// The rpc package contains helpers for connecting to the gcert service over gRPC.
 package rpc
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/jmgilman/gcli/logging"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net"
+	"time"
 )
 
-func Dial(host string, insecure bool) (conn *grpc.ClientConn, err error) {
-	var option grpc.DialOption
-	if insecure {
-		option = grpc.WithInsecure()
+// Option configures how Dial connects to a server.
+type Option func(*options)
+
+type options struct {
+	insecure      bool
+	tls           *tls.Config
+	timeout       time.Duration
+	block         bool
+	keepalive     *keepalive.ClientParameters
+	maxMsgSize    int
+	userAgent     string
+	healthCheck   bool
+	healthService string
+	dialOptions   []grpc.DialOption
+}
+
+// WithInsecure disables transport security.
+func WithInsecure() Option {
+	return func(o *options) {
+		o.insecure = true
+	}
+}
+
+// HandshakeError is returned by Dial when the TLS handshake with the server fails, i.e. because the server doesn't use
+// TLS or its certificate isn't trusted.
+type HandshakeError struct {
+	Err error
+}
+
+func (e *HandshakeError) Error() string {
+	return "TLS handshake failed: " + e.Err.Error()
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary returns false so a blocking dial fails with the handshake error rather than retrying until it times out,
+// since retrying won't make the server use TLS or trust its certificate.
+func (e *HandshakeError) Temporary() bool {
+	return false
+}
+
+// handshakeCredentials wraps the errors of failed TLS handshakes in a HandshakeError.
+type handshakeCredentials struct {
+	credentials.TransportCredentials
+}
+
+func (c handshakeCredentials) ClientHandshake(ctx context.Context, authority string,
+	conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	secure, info, err := c.TransportCredentials.ClientHandshake(ctx, authority, conn)
+	if err != nil {
+		return nil, nil, &HandshakeError{Err: err}
+	}
+	return secure, info, nil
+}
+
+func (c handshakeCredentials) Clone() credentials.TransportCredentials {
+	return handshakeCredentials{c.TransportCredentials.Clone()}
+}
+
+// WithTLS secures the connection with TLS using the given config. The server certificate is verified against the roots
+// of the config, or the system roots if it has none.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// TLSConfig returns a TLS config which verifies the server certificate against the CA certificates in the given PEM
+// file, or the system roots if it's empty. The given server name, if any, is verified instead of the dialed host.
+func TLSConfig(caCert string, serverName string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName}
+	if caCert == "" {
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(caCert)
+	if err != nil {
+		return &tls.Config{}, err
+	}
+
+	config.RootCAs = x509.NewCertPool()
+	if !config.RootCAs.AppendCertsFromPEM(data) {
+		return &tls.Config{}, fmt.Errorf("no PEM certificates were found in %s", caCert)
+	}
+	return config, nil
+}
+
+// WithTimeout limits how long Dial waits for the connection to be established (and the health check to pass). It
+// implies WithBlock, since without blocking Dial returns before connecting.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+		o.block = true
+	}
+}
+
+// WithBlock makes Dial wait until the connection is established rather than connecting in the background. Without it,
+// an unreachable server is only reported when the first call is made.
+func WithBlock() Option {
+	return func(o *options) {
+		o.block = true
+	}
+}
+
+// WithKeepalive pings the server after the given interval without activity, closing the connection if a ping isn't
+// acknowledged within the given timeout. This detects connections silently dropped by the network (i.e. by a NAT).
+func WithKeepalive(interval time.Duration, timeout time.Duration) Option {
+	return func(o *options) {
+		o.keepalive = &keepalive.ClientParameters{
+			Time:    interval,
+			Timeout: timeout,
+		}
+	}
+}
+
+// WithMaxMessageSize sets the maximum size in bytes of the messages sent to and received from the server.
+func WithMaxMessageSize(size int) Option {
+	return func(o *options) {
+		o.maxMsgSize = size
+	}
+}
+
+// WithUserAgent sets the user agent sent with every call.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithHealthCheck makes Dial check the given service (an empty service checks the server as a whole) with the gRPC
+// health checking protocol (grpc.health.v1) after connecting, failing if it isn't serving. Servers which don't
+// implement the protocol are assumed to be healthy. It implies WithBlock.
+func WithHealthCheck(service string) Option {
+	return func(o *options) {
+		o.healthCheck = true
+		o.healthService = service
+		o.block = true
+	}
+}
+
+// WithDialOptions passes the given options directly to grpc.DialContext (i.e. interceptors).
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// Dial connects to the server at the given host configured with the given options.
+func Dial(host string, opts ...Option) (*grpc.ClientConn, error) {
+	return DialContext(context.Background(), host, opts...)
+}
+
+// DialContext is the same as Dial except blocking dials are cancelled when the given context is done.
+func DialContext(ctx context.Context, host string, opts ...Option) (*grpc.ClientConn, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	logger := logging.L().Named("rpc")
+	logger.Debug("dialing gcert server", "host", host, "insecure", o.insecure, "tls", o.tls != nil, "block", o.block,
+		"timeout", o.timeout)
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
 	}
 
-	conn, err = grpc.Dial(host, option)
+	conn, err := grpc.DialContext(ctx, host, o.grpcOptions()...)
 	if err != nil {
-		return &grpc.ClientConn{}, err
+		// gRPC connection errors can't be unwrapped, so the error which caused them (i.e. a refused connection or
+		// HandshakeError) is returned instead
+		var connErr interface{ Origin() error }
+		if errors.As(err, &connErr) && connErr.Origin() != nil {
+			err = connErr.Origin()
+		}
+		return &grpc.ClientConn{}, fmt.Errorf("unable to connect to %s: %w", host, err)
+	}
+
+	if o.healthCheck {
+		if err := checkHealth(ctx, conn, o.healthService); err != nil {
+			conn.Close()
+			return &grpc.ClientConn{}, err
+		}
+	}
+
+	return conn, nil
+}
+
+// grpcOptions returns the grpc.DialOption for each of the options.
+func (o *options) grpcOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if o.insecure {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if o.tls != nil {
+		opts = append(opts, grpc.WithTransportCredentials(handshakeCredentials{credentials.NewTLS(o.tls)}))
+	}
+	if o.block {
+		// Errors which retrying won't resolve (i.e. a refused connection or failed TLS handshake) are returned immediately
+		opts = append(opts, grpc.WithBlock(), grpc.FailOnNonTempDialError(true))
+	}
+	if o.keepalive != nil {
+		opts = append(opts, grpc.WithKeepaliveParams(*o.keepalive))
+	}
+	if o.maxMsgSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(o.maxMsgSize),
+			grpc.MaxCallSendMsgSize(o.maxMsgSize)))
+	}
+	if o.userAgent != "" {
+		opts = append(opts, grpc.WithUserAgent(o.userAgent))
+	}
+	return append(opts, o.dialOptions...)
+}
+
+// checkHealth returns an error with the Unavailable code if the given service isn't serving.
+func checkHealth(ctx context.Context, conn *grpc.ClientConn, service string) error {
+	logger := logging.L().Named("rpc")
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if status.Code(err) == codes.Unimplemented {
+		logger.Debug("server doesn't support health checks, assuming it's healthy", "target", conn.Target())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("checked server health", "target", conn.Target(), "service", service, "status", resp.Status)
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return status.Errorf(codes.Unavailable, "the server at %s is %s", conn.Target(), resp.Status)
 	}
-	return
+	return nil
 }
@@ -1,14 +1,182 @@
 package rpc
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
-
 func TestDial(t *testing.T) {
-	conn, err := Dial("fakehost", true)
+	conn, err := Dial("fakehost", WithInsecure())
 
 	assert.Nil(t, err)
 	assert.Equal(t, conn.Target(), "fakehost")
-}
\ No newline at end of file
+}
+
+func newHealthServer(t *testing.T, register bool) (string, *health.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	if register {
+		healthpb.RegisterHealthServer(server, healthServer)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), healthServer
+}
+
+func TestDial_Options(t *testing.T) {
+	addr, healthServer := newHealthServer(t, true)
+	healthServer.SetServingStatus("gcert", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("down", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	t.Run("Test blocking dial", func(t *testing.T) {
+		conn, err := Dial(addr, WithInsecure(), WithTimeout(5*time.Second), WithKeepalive(time.Minute, 10*time.Second),
+			WithMaxMessageSize(1024), WithUserAgent("gcli"))
+		assert.Nil(t, err)
+		conn.Close()
+	})
+	t.Run("Test dial timeout", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener.Close()
+
+		start := time.Now()
+		_, err = Dial(listener.Addr().String(), WithInsecure(), WithTimeout(200*time.Millisecond))
+		assert.NotNil(t, err)
+		assert.True(t, time.Since(start) < 5*time.Second)
+	})
+	t.Run("Test health check", func(t *testing.T) {
+		conn, err := Dial(addr, WithInsecure(), WithTimeout(5*time.Second), WithHealthCheck("gcert"))
+		assert.Nil(t, err)
+		conn.Close()
+	})
+	t.Run("Test health check of a service which isn't serving", func(t *testing.T) {
+		_, err := Dial(addr, WithInsecure(), WithTimeout(5*time.Second), WithHealthCheck("down"))
+		assert.NotNil(t, err)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+	t.Run("Test health check of a server without health checking", func(t *testing.T) {
+		addr, _ := newHealthServer(t, false)
+		conn, err := Dial(addr, WithInsecure(), WithTimeout(5*time.Second), WithHealthCheck(""))
+		assert.Nil(t, err)
+		conn.Close()
+	})
+}
+
+// newTLSServer starts a gRPC server using the certificate of a test HTTPS server (valid for 127.0.0.1 and example.com)
+// and returns its address along with the certificate.
+func newTLSServer(t *testing.T) (string, *x509.Certificate) {
+	https := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	https.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&https.TLS.Certificates[0])))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), https.Certificate()
+}
+
+func TestDial_TLS(t *testing.T) {
+	addr, cert := newTLSServer(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	t.Run("Test trusted server", func(t *testing.T) {
+		conn, err := Dial(addr, WithTLS(&tls.Config{RootCAs: roots}), WithTimeout(5*time.Second), WithHealthCheck(""))
+		assert.Nil(t, err)
+		conn.Close()
+	})
+	t.Run("Test server name", func(t *testing.T) {
+		conn, err := Dial(addr, WithTLS(&tls.Config{RootCAs: roots, ServerName: "example.com"}),
+			WithTimeout(5*time.Second), WithHealthCheck(""))
+		assert.Nil(t, err)
+		conn.Close()
+
+		_, err = Dial(addr, WithTLS(&tls.Config{RootCAs: roots, ServerName: "other.test"}),
+			WithTimeout(500*time.Millisecond))
+		assert.NotNil(t, err)
+	})
+	t.Run("Test untrusted server", func(t *testing.T) {
+		start := time.Now()
+		_, err := Dial(addr, WithTLS(&tls.Config{RootCAs: x509.NewCertPool()}), WithTimeout(5*time.Second))
+		var handshakeErr *HandshakeError
+		assert.True(t, errors.As(err, &handshakeErr), err)
+		assert.True(t, time.Since(start) < 4*time.Second)
+	})
+	t.Run("Test server without TLS", func(t *testing.T) {
+		plain, _ := newHealthServer(t, true)
+
+		start := time.Now()
+		_, err := Dial(plain, WithTLS(&tls.Config{RootCAs: roots}), WithTimeout(5*time.Second))
+		var handshakeErr *HandshakeError
+		assert.True(t, errors.As(err, &handshakeErr), err)
+		assert.True(t, time.Since(start) < 4*time.Second)
+	})
+	t.Run("Test insecure dial of a TLS server", func(t *testing.T) {
+		_, err := Dial(addr, WithInsecure(), WithTimeout(500*time.Millisecond), WithHealthCheck(""))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestTLSConfig(t *testing.T) {
+	_, cert := newTLSServer(t)
+
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caCert := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := ioutil.WriteFile(invalid, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := TLSConfig(caCert, "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", config.ServerName)
+	assert.Len(t, config.RootCAs.Subjects(), 1)
+
+	config, err = TLSConfig("", "")
+	assert.Nil(t, err)
+	assert.Nil(t, config.RootCAs)
+
+	_, err = TLSConfig(invalid, "")
+	assert.NotNil(t, err)
+	_, err = TLSConfig(filepath.Join(dir, "missing.pem"), "")
+	assert.NotNil(t, err)
+}
@@ -6,6 +6,9 @@ package auth
 //go:generate moq -out ../../internal/mocks/authinterface.go -pkg mocks . Auth
 // Auth represents a form of authenticating with a Vault instance. See UserPassAuth for an example of how to properly
 // implement this interface.
+//
+// The path returned by GetPath must be relative to the namespace the client is configured with (i.e. it must not be
+// prefixed with a namespace) as it's resolved inside that namespace when logging in.
 type Auth interface {
 	Name() string
 	GetData(map[string]*Detail) map[string]interface{}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"github.com/jmgilman/gcli/logging"
 )
 
 // UserPassAuth represents a form of authentication that takes a username and password.
@@ -51,7 +52,9 @@ func (u *UserPassAuth) AuthDetails() map[string]*Detail {
 // GetPath returns the Vault path to write to for performing this type of authentication
 // (i.e. auth/userpass/login/user).
 func (u *UserPassAuth) GetPath(details map[string]*Detail) string {
-	return fmt.Sprintf("auth/%s/login/%s", u.mount, details["username"].Value)
+	path := fmt.Sprintf("auth/%s/login/%s", u.mount, details["username"].Value)
+	logging.L().Named("auth").Trace("built login path", "method", u.name, "path", path)
+	return path
 }
 
 // GetData returns a map of JSON data that will be written to the path returned by GetPath.
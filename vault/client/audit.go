@@ -0,0 +1,17 @@
+package client
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/audit"
+)
+
+// SetAuditLog records every request made by the underlying API client in the given audit log. Each attempt of a
+// retried request is recorded separately.
+func (c *VaultClient) SetAuditLog(l *audit.Log) error {
+	if c.httpClient == nil {
+		return fmt.Errorf("audit logging is only supported by clients created with NewClient")
+	}
+
+	c.httpClient.Transport = &audit.Transport{Base: c.httpClient.Transport, Log: l}
+	return nil
+}
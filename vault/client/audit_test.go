@@ -0,0 +1,57 @@
+package client_test
+
+import (
+	"github.com/jmgilman/gcli/audit"
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVaultClient_SetAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"password": "hunter2"}}`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	auditLog, err := audit.Open(path, []byte("key"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	vaultClient, err := client.NewDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vaultClient.SetConfigValues(server.URL, "s.tG3fu9ZfD6kHqdDbA4fT2hJx"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, vaultClient.SetAuditLog(auditLog))
+
+	_, err = vaultClient.ReadSecret("secret/test")
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), `"path":"secret/`+auditLog.Hash("test")+`"`)
+	assert.Contains(t, string(data), auditLog.Hash("s.tG3fu9ZfD6kHqdDbA4fT2hJx"))
+	assert.NotContains(t, string(data), "s.tG3fu9ZfD6kHqdDbA4fT2hJx")
+	assert.NotContains(t, string(data), "hunter2")
+
+	assert.NotNil(t, client.NewClientWithAPI(nil).SetAuditLog(auditLog))
+}
@@ -3,25 +3,55 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/retry"
 	"github.com/jmgilman/gcli/vault/auth"
+	"net/http"
+	"strings"
+	"time"
 )
 
 // VaultClient is a small wrapper around the Vault API client. It provides additional functionality needed by vssh such
 // as handling authentication a client and signing SSH public keys.
 type VaultClient struct {
-	api *api.Client
+	api        *api.Client
+	httpClient *http.Client
+	failover   *failoverTransport
+	nodes      []NodeHealth
 }
 
-// NewClient returns a new VaultClient with the underlying API client configured with the given api.Config.
+// NewClient returns a new VaultClient with the underlying API client configured with the given api.Config. The
+// transport of the config's HTTP client is wrapped to report untrusted TLS certificates as an
+// UntrustedCertificateError and to support failing over between the addresses given to SetAddresses. Unless the config
+// has its own CheckRetry, requests are only retried when they fail with an error the retry package considers transient.
 func NewClient(c *api.Config) (*VaultClient, error) {
+	if c == nil {
+		c = api.DefaultConfig()
+	}
+	if c.CheckRetry == nil {
+		c.CheckRetry = checkRetry(retry.DefaultPolicy())
+	}
+
 	apiClient, err := api.NewClient(c)
 	if err != nil {
 		return &VaultClient{}, err
 	}
+
+	// The API client expects an *http.Transport when it's created, so it can only be wrapped afterwards
+	failover := &failoverTransport{base: &tlsErrorTransport{base: c.HttpClient.Transport}}
+	c.HttpClient.Transport = failover
+
 	return &VaultClient{
-		api: apiClient,
+		api:        apiClient,
+		httpClient: c.HttpClient,
+		failover:   failover,
 	}, nil
 }
 
@@ -36,19 +66,32 @@ func NewDefaultClient() (*VaultClient, error) {
 }
 
 // Login takes an authentication type along with its associated details and attempts to authenticate against the
-// configured Vault instance. If authentication is successful, the token returned from the Vault instance will be
-// automatically set to the underlying API client.
+// configured Vault instance. The path returned by the authentication type is resolved inside the namespace configured
+// with SetNamespace, so the returned token is scoped to that namespace. If authentication is successful, the token
+// returned from the Vault instance will be automatically set to the underlying API client. Errors are returned as a
+// failure.Error, with rejected credentials having the failure.AuthFailed kind.
 func (c *VaultClient) Login(a auth.Auth, d map[string]*auth.Detail) error {
-	secret, err := c.api.Logical().Write(a.GetPath(d), a.GetData(d))
+	return c.LoginWithContext(context.Background(), a, d)
+}
+
+// LoginWithContext is the same as Login except the request is cancelled when the given context is done.
+func (c *VaultClient) LoginWithContext(ctx context.Context, a auth.Auth, d map[string]*auth.Detail) error {
+	logger := logging.L().Named("client")
+	path := a.GetPath(d)
+	logger.Debug("logging in", "path", path, "namespace", c.Namespace())
+
+	secret, err := c.writeWithContext(ctx, path, a.GetData(d))
 
 	if err != nil {
-		return err
+		logger.Debug("login failed", "path", path, "error", err)
+		return loginError(err)
 	}
 
-	if secret.Auth == nil {
-		return fmt.Errorf("login returned an empty token")
+	if secret == nil || secret.Auth == nil {
+		return failure.New(failure.AuthFailed, fmt.Errorf("login returned an empty token"), loginHint)
 	}
 
+	logger.Info("logged in", "path", path, "policies", secret.Auth.Policies, "ttl", secret.Auth.LeaseDuration)
 	c.api.SetToken(secret.Auth.ClientToken)
 	return nil
 }
@@ -56,12 +99,14 @@ func (c *VaultClient) Login(a auth.Auth, d map[string]*auth.Detail) error {
 // SignPubKey will use the underlying API client to attempt to sign the given SSH public key with the given role and
 // mount point.
 func (c *VaultClient) SignPubKey(mount string, role string, key []byte) (string, error) {
-	var ssh *api.SSH
-	// The SSH method sets the mount to its default value of "ssh"
+	return c.SignPubKeyWithContext(context.Background(), mount, role, key)
+}
+
+// SignPubKeyWithContext is the same as SignPubKey except the request is cancelled when the given context is done.
+func (c *VaultClient) SignPubKeyWithContext(ctx context.Context, mount string, role string, key []byte) (string, error) {
+	// Use the same default mount as the SSH method of the API client
 	if mount == "" {
-		ssh = c.api.SSH()
-	} else {
-		ssh = c.api.SSHWithMountPoint(mount)
+		mount = "ssh"
 	}
 
 	data := map[string]interface{} {
@@ -69,8 +114,7 @@ func (c *VaultClient) SignPubKey(mount string, role string, key []byte) (string,
 		"cert_type": "user",
 	}
 
-	// SignKey is a nice API wrapper which handles most of the logic for signing a key
-	result, err := ssh.SignKey(role, data)
+	result, err := c.writeWithContext(ctx, fmt.Sprintf("%s/sign/%s", mount, role), data)
 	if err != nil {
 		return "", err
 	}
@@ -87,11 +131,118 @@ func (c *VaultClient) SignPubKey(mount string, role string, key []byte) (string,
 	return signedKey, nil
 }
 
+// ReadSecret reads the secret at the given path and returns its data. An error with the failure.NotFound kind is
+// returned if no secret exists at the given path.
+func (c *VaultClient) ReadSecret(path string) (map[string]interface{}, error) {
+	secret, err := c.api.Logical().Read(path)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return map[string]interface{}{}, failure.New(failure.NotFound, fmt.Errorf("no secret was found at %s", path),
+			"check the path is correct")
+	}
+
+	return secret.Data, nil
+}
+
+// ListSecrets returns the keys listed at the given path. Keys ending with a slash are folders containing more keys. An
+// empty list is returned if nothing exists at the given path.
+func (c *VaultClient) ListSecrets(path string) ([]string, error) {
+	secret, err := c.api.Logical().List(path)
+	if err != nil {
+		return []string{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return []string{}, nil
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if k, ok := key.(string); ok {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+// ListCertificates returns the domain of every certificate written by the gcert service, walking any folders found
+// under cert.BasePath.
+func (c *VaultClient) ListCertificates() ([]string, error) {
+	var domains []string
+
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		keys, err := c.ListSecrets(cert.Path(prefix))
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if strings.HasSuffix(key, "/") {
+				if err := walk(prefix + key); err != nil {
+					return err
+				}
+				continue
+			}
+			domains = append(domains, prefix+key)
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return []string{}, err
+	}
+	return domains, nil
+}
+
+// GetCertificate reads the certificate written by the gcert service for the given domain.
+func (c *VaultClient) GetCertificate(domain string) (*cert.Certificate, error) {
+	data, err := c.ReadSecret(cert.Path(domain))
+	if err != nil {
+		return &cert.Certificate{}, err
+	}
+
+	return cert.NewCertificateFromData(domain, data)
+}
+
+// SSHCAPublicKey returns the public key of the CA configured for the SSH secrets engine at the given mount point. An
+// empty mount defaults to "ssh".
+func (c *VaultClient) SSHCAPublicKey(mount string) (string, error) {
+	if mount == "" {
+		mount = "ssh"
+	}
+
+	data, err := c.ReadSecret(mount + "/config/ca")
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := data["public_key"].(string)
+	if !ok || key == "" {
+		return "", fmt.Errorf("no public key was returned from the server")
+	}
+
+	return key, nil
+}
+
 // Authenticated performs a lookup of the underlying API client which by nature requires a valid token. If the lookup
 // fails it will return false, indicating the client does not have a valid token. If the lookup succeeds, it returns
 // true.
 func (c *VaultClient) Authenticated() bool {
-	_, err := c.api.Auth().Token().LookupSelf()
+	return c.AuthenticatedWithContext(context.Background())
+}
+
+// AuthenticatedWithContext is the same as Authenticated except the lookup is cancelled when the given context is done,
+// in which case it returns false.
+func (c *VaultClient) AuthenticatedWithContext(ctx context.Context) bool {
+	resp, err := c.api.RawRequestWithContext(ctx, c.api.NewRequest("GET", "/v1/auth/token/lookup-self"))
+	if resp != nil {
+		defer resp.Body.Close()
+	}
 	if err != nil {
 		return false
 	} else {
@@ -102,11 +253,25 @@ func (c *VaultClient) Authenticated() bool {
 // Available checks if the configured Vault instance is either sealed or not initialized, returning false if either of
 // those conditions are true.
 func (c *VaultClient) Available() (bool, error) {
-	status, err := c.api.Sys().SealStatus()
+	return c.AvailableWithContext(context.Background())
+}
+
+// AvailableWithContext is the same as Available except the seal status request is cancelled when the given context is
+// done.
+func (c *VaultClient) AvailableWithContext(ctx context.Context) (bool, error) {
+	resp, err := c.api.RawRequestWithContext(ctx, c.api.NewRequest("GET", "/v1/sys/seal-status"))
+	if resp != nil {
+		defer resp.Body.Close()
+	}
 	if err != nil {
 		return false, err
 	}
 
+	var status api.SealStatusResponse
+	if err := resp.DecodeJSON(&status); err != nil {
+		return false, err
+	}
+
 	if !status.Sealed && status.Initialized {
 		return true, nil
 	}
@@ -129,6 +294,33 @@ func (c *VaultClient) SetConfigValues(server string, token string) error {
 	return nil
 }
 
+// SetNamespace sets the Vault Enterprise namespace that every API call made by the underlying API client is made in.
+// Paths given to the client (i.e. auth/userpass/login/user) are resolved relative to this namespace. An empty namespace
+// clears it, causing API calls to be made in the root namespace.
+func (c *VaultClient) SetNamespace(namespace string) {
+	if namespace != "" {
+		c.api.SetNamespace(namespace)
+		return
+	}
+
+	headers := c.api.Headers()
+	if headers != nil {
+		headers.Del(consts.NamespaceHeaderName)
+		c.api.SetHeaders(headers)
+	}
+}
+
+// Namespace returns the Vault Enterprise namespace configured for the underlying API client.
+func (c *VaultClient) Namespace() string {
+	return c.api.Headers().Get(consts.NamespaceHeaderName)
+}
+
+// SetTimeout sets the maximum time each request made by the underlying API client is given before it's abandoned,
+// including requests made by methods which don't take a context.
+func (c *VaultClient) SetTimeout(timeout time.Duration) {
+	c.api.SetClientTimeout(timeout)
+}
+
 // Address returns the Vault instance address configured for the underlying API client.
 func (c *VaultClient) Address() string {
 	return c.api.Address()
@@ -137,4 +329,39 @@ func (c *VaultClient) Address() string {
 // Token returns the token configured for the underlying API client.
 func (c *VaultClient) Token() string {
 	return c.api.Token()
+}
+
+// loginHint is the hint given when Vault rejects the credentials used to log in.
+const loginHint = "check the credentials are correct and that the auth method is enabled at the expected path"
+
+// loginError maps an error returned while logging in to a failure.Error. Vault rejects invalid credentials with either
+// a 400, 401, or 403 depending on the auth method, all of which mean authentication failed.
+func loginError(err error) error {
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.StatusCode {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return failure.New(failure.AuthFailed, err, loginHint)
+		}
+	}
+	return failure.FromVault(err)
+}
+
+// writeWithContext writes the given data to the given path the same way the Write method of the API client's logical
+// backend does, except the request is cancelled when the given context is done.
+func (c *VaultClient) writeWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	r := c.api.NewRequest("PUT", "/v1/"+path)
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.api.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ParseSecret(resp.Body)
 }
\ No newline at end of file
@@ -1,17 +1,22 @@
 package client_test
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/builtin/logical/pki"
 	"github.com/hashicorp/vault/builtin/logical/ssh"
+	"github.com/hashicorp/vault/builtin/logical/transit"
 	"github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
+	"github.com/jmgilman/gcli/failure"
 	"github.com/jmgilman/gcli/internal/mocks"
 	"github.com/jmgilman/gcli/vault/auth"
 	"github.com/jmgilman/gcli/vault/client"
@@ -19,8 +24,11 @@ import (
 	"github.com/stretchr/testify/suite"
 	cssh "golang.org/x/crypto/ssh"
 	"net"
+	nethttp "net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 type ClientTestSuite struct {
@@ -59,6 +67,8 @@ func (suite *ClientTestSuite) NewVaultServer() (net.Listener, *api.Client, [][]b
 		},
 		LogicalBackends: map[string]logical.Factory {
 			"ssh": ssh.Factory,
+			"pki": pki.Factory,
+			"transit": transit.Factory,
 		},
 	}
 	core, keyShares, rootToken := vault.TestCoreUnsealedWithConfig(t, coreConfig)
@@ -107,6 +117,47 @@ func (suite *ClientTestSuite) NewVaultServer() (net.Listener, *api.Client, [][]b
 		t.Fatal(err)
 	}
 
+	// Setup PKI backend
+	err = apiClient.Sys().Mount("pki", &api.MountInput{Type: "pki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = apiClient.Logical().Write("pki/root/generate/internal", map[string]interface{}{"common_name": "lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = apiClient.Logical().Write("pki/roles/test", map[string]interface{}{
+		"allowed_domains":  "lab",
+		"allow_subdomains": true,
+		"max_ttl":          "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = apiClient.Logical().Write("pki/roles/sign", map[string]interface{}{
+		"allowed_domains":  "lab",
+		"allow_subdomains": true,
+		"key_type":         "any",
+		"max_ttl":          "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup transit backend
+	err = apiClient.Sys().Mount("transit", &api.MountInput{Type: "transit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = apiClient.Logical().Write("transit/keys/test", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = apiClient.Logical().Write("transit/keys/signer", map[string]interface{}{"type": "ed25519"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	return ln, apiClient, keyShares
 }
 
@@ -183,15 +234,42 @@ func (suite *ClientTestSuite) TestVaultClient_Login() {
 
 		err := vaultClient.Login(suite.NewMockAuth("wrongpassword"), details)
 		assert.Empty(t, vaultClient.Token())
-		switch err := err.(type) {
-		case *api.ResponseError:
-			assert.Equal(t, err.StatusCode, 400)
-		default:
+
+		var respErr *api.ResponseError
+		if !errors.As(err, &respErr) {
 			t.Fatal(err)
 		}
+		assert.Equal(t, respErr.StatusCode, 400)
+		assert.Equal(t, failure.AuthFailed, failure.Classify(err).Kind)
 	})
 }
 
+func (suite *ClientTestSuite) TestVaultClient_LoginWithNamespace() {
+	t := suite.T()
+
+	// Vault OSS doesn't support namespaces so capture the login request instead
+	var namespace, path string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		namespace = r.Header.Get("X-Vault-Namespace")
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "namespaced"}}`))
+	}))
+	defer server.Close()
+
+	vaultClient, err := client.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultClient.SetNamespace("lab/team")
+
+	err = vaultClient.Login(suite.NewMockAuth("password"), map[string]*auth.Detail{})
+	assert.Nil(t, err)
+	assert.Equal(t, "lab/team", namespace)
+	assert.Equal(t, "/v1/auth/userpass/login/test", path)
+	assert.Equal(t, "namespaced", vaultClient.Token())
+}
+
 func (suite *ClientTestSuite) TestSignPubKey() {
 	suite.apiClient.SetToken(suite.rootToken)
 	vaultClient := client.NewClientWithAPI(suite.apiClient)
@@ -206,6 +284,96 @@ func (suite *ClientTestSuite) TestSignPubKey() {
 	assert.NotEmpty(suite.T(), result)
 }
 
+func (suite *ClientTestSuite) TestReadSecret() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	_, err := suite.apiClient.Logical().Write("secret/test", map[string]interface{}{"field": "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test with an existing secret", func(t *testing.T) {
+		data, err := vaultClient.ReadSecret("secret/test")
+		assert.Nil(t, err)
+		assert.Equal(t, "value", data["field"])
+	})
+	t.Run("Test with a missing secret", func(t *testing.T) {
+		_, err := vaultClient.ReadSecret("secret/missing")
+		assert.NotNil(t, err)
+	})
+}
+
+func (suite *ClientTestSuite) TestGetCertificate() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	data := map[string]interface{}{
+		"cert_url":           "https://acme/cert",
+		"cert_stable_url":    "https://acme/stable",
+		"certificate":        base64.StdEncoding.EncodeToString([]byte("certificate")),
+		"private_key":        base64.StdEncoding.EncodeToString([]byte("private key")),
+		"issuer_certificate": base64.StdEncoding.EncodeToString([]byte("issuer")),
+	}
+	_, err := suite.apiClient.Logical().Write("secret/ssl/test.example.com", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := vaultClient.GetCertificate("test.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "test.example.com", result.Domain)
+	assert.Equal(t, []byte("certificate"), result.Certificate)
+}
+
+func (suite *ClientTestSuite) TestListCertificates() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	for _, path := range []string{"secret/ssl/a.example.com", "secret/ssl/lab/b.example.com"} {
+		_, err := suite.apiClient.Logical().Write(path, map[string]interface{}{"certificate": ""})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	domains, err := vaultClient.ListCertificates()
+	assert.Nil(t, err)
+	assert.Contains(t, domains, "a.example.com")
+	assert.Contains(t, domains, "lab/b.example.com")
+	assert.NotContains(t, domains, "lab/")
+
+	keys, err := vaultClient.ListSecrets("secret/missing")
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
+func (suite *ClientTestSuite) TestSSHCAPublicKey() {
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	result, err := vaultClient.SSHCAPublicKey("ssh")
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), result, "ssh-rsa")
+}
+
+func (suite *ClientTestSuite) TestSetNamespace() {
+	t := suite.T()
+	vaultClient, err := client.NewClient(&api.Config{Address: "http://127.1.1:8200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vaultClient.SetNamespace("lab")
+	assert.Equal(t, "lab", vaultClient.Namespace())
+
+	vaultClient.SetNamespace("")
+	assert.Empty(t, vaultClient.Namespace())
+}
+
 func (suite *ClientTestSuite) TestAuthenticated() {
 	t := suite.T()
 	vaultClient := client.NewClientWithAPI(suite.apiClient)
@@ -252,4 +420,54 @@ func (suite *ClientTestSuite) TestAvailable() {
 	})
 
 	// TODO(jmgilman): Implement a test for an uninitialized vault
-}
\ No newline at end of file
+}
+func (suite *ClientTestSuite) TestWithContext() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("Test login with a cancelled context", func(t *testing.T) {
+		err := vaultClient.LoginWithContext(ctx, suite.NewMockAuth("password"), map[string]*auth.Detail{})
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, suite.rootToken, vaultClient.Token())
+	})
+	t.Run("Test signing with a cancelled context", func(t *testing.T) {
+		pubKey, err := suite.NewSSHPubKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = vaultClient.SignPubKeyWithContext(ctx, "ssh", "test", pubKey)
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+	t.Run("Test availability with a cancelled context", func(t *testing.T) {
+		status, err := vaultClient.AvailableWithContext(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.False(t, status)
+	})
+	t.Run("Test authentication with a cancelled context", func(t *testing.T) {
+		assert.False(t, vaultClient.AuthenticatedWithContext(ctx))
+	})
+	t.Run("Test with a context that times out", func(t *testing.T) {
+		// Accept connections but never respond
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		slowClient, err := client.NewClient(&api.Config{Address: "http://" + listener.Addr().String()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = slowClient.AvailableWithContext(ctx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
@@ -0,0 +1,276 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/jmgilman/gcli/logging"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// probeTimeout is how long a node is given to respond to a health probe.
+const probeTimeout = 5 * time.Second
+
+// ErrNoAvailableNodes is returned by SetAddresses when none of the given nodes are available.
+var ErrNoAvailableNodes = errors.New("none of the given Vault nodes are available")
+
+// NodeHealth represents the health of a single node of a Vault cluster as reported by its sys/health endpoint.
+type NodeHealth struct {
+	Address            string
+	Initialized        bool
+	Sealed             bool
+	Standby            bool
+	PerformanceStandby bool
+	Err                error
+}
+
+// Active returns true if the node is reachable, initialized, unsealed, and the active node of its cluster.
+func (h *NodeHealth) Active() bool {
+	return h.Available() && !h.Standby
+}
+
+// Available returns true if the node is reachable, initialized, and unsealed. Standby nodes are available since they
+// forward requests to the active node.
+func (h *NodeHealth) Available() bool {
+	return h.Err == nil && h.Initialized && !h.Sealed
+}
+
+// rank orders nodes by preference: the active node, then performance standbys, then standbys, and then any nodes
+// which are sealed, uninitialized, or unreachable.
+func (h *NodeHealth) rank() int {
+	switch {
+	case h.Active():
+		return 0
+	case h.Available() && h.PerformanceStandby:
+		return 1
+	case h.Available():
+		return 2
+	case h.Err == nil:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// ProbeNodes checks the health of each of the given Vault addresses the same way Available checks the seal status of
+// the configured Vault instance. Probes are sent directly to each node and never fail over.
+func (c *VaultClient) ProbeNodes(addresses []string) []NodeHealth {
+	results := make([]NodeHealth, len(addresses))
+
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			results[i] = c.probeNode(address)
+		}(i, address)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// SortNodes sorts the given nodes in order of preference: the active node, then performance standbys, then standbys,
+// and then any nodes which are sealed, uninitialized, or unreachable.
+func SortNodes(health []NodeHealth) {
+	sort.SliceStable(health, func(i, j int) bool {
+		return health[i].rank() < health[j].rank()
+	})
+}
+
+// SetAddresses configures the VaultClient with the addresses of every node of a Vault cluster. Each node is probed and
+// requests are sent to the most preferred available node, preferring the active node. If a node can't be connected to
+// or responds as sealed, requests fail over to the next node. The health of every node is returned in order of
+// preference, and an error is returned if none of the nodes are available.
+func (c *VaultClient) SetAddresses(addresses []string) ([]NodeHealth, error) {
+	if c.failover == nil {
+		return []NodeHealth{}, fmt.Errorf("failover is only supported by clients created with NewClient")
+	}
+
+	health := c.ProbeNodes(addresses)
+	SortNodes(health)
+	c.nodes = health
+
+	logger := logging.L().Named("client")
+	for _, node := range health {
+		logger.Debug("probed Vault node", "address", node.Address, "initialized", node.Initialized,
+			"sealed", node.Sealed, "standby", node.Standby, "error", node.Err)
+	}
+
+	nodes := make([]*url.URL, len(health))
+	for i, node := range health {
+		u, err := url.Parse(node.Address)
+		if err != nil {
+			return health, err
+		}
+		nodes[i] = u
+	}
+
+	if len(nodes) > 0 {
+		if err := c.api.SetAddress(nodes[0].String()); err != nil {
+			return health, err
+		}
+	}
+	c.failover.setNodes(nodes)
+
+	if len(health) == 0 || !health[0].Available() {
+		return health, ErrNoAvailableNodes
+	}
+
+	return health, nil
+}
+
+// Nodes returns the health of every node given to SetAddresses in order of preference, as probed when they were set. It
+// returns an empty slice if SetAddresses wasn't called.
+func (c *VaultClient) Nodes() []NodeHealth {
+	return append([]NodeHealth{}, c.nodes...)
+}
+
+// Node returns the address of the Vault node which served the last request made by the VaultClient. Each request is
+// also logged at debug level along with the node which served it.
+func (c *VaultClient) Node() string {
+	if c.failover == nil {
+		return c.Address()
+	}
+	return c.failover.lastServed()
+}
+
+func (c *VaultClient) probeNode(address string) NodeHealth {
+	health := NodeHealth{Address: address}
+
+	config := &api.Config{
+		Address:    address,
+		MaxRetries: 0,
+		Timeout:    probeTimeout,
+	}
+	if c.failover != nil {
+		config.HttpClient = &http.Client{Transport: c.failover.base}
+	}
+
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		health.Err = err
+		return health
+	}
+
+	response, err := apiClient.Sys().Health()
+	if err != nil {
+		health.Err = err
+		return health
+	}
+
+	health.Initialized = response.Initialized
+	health.Sealed = response.Sealed
+	health.Standby = response.Standby
+	health.PerformanceStandby = response.PerformanceStandby
+	return health
+}
+
+// failoverTransport wraps an http.RoundTripper, sending each request to the configured nodes in order until one of them
+// serves it. A node is skipped if it can't be connected to or responds with 503 (i.e. it's sealed). The node which
+// serves a request is moved to the front so subsequent requests go to it first.
+type failoverTransport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	nodes  []*url.URL
+	served string
+}
+
+func (t *failoverTransport) setNodes(nodes []*url.URL) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nodes = nodes
+}
+
+func (t *failoverTransport) lastServed() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.served
+}
+
+func (t *failoverTransport) setServed(r *http.Request, node *url.URL) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.served = node.Scheme + "://" + node.Host
+	logging.L().Named("client").Debug("Vault request served", "method", r.Method, "node", t.served)
+	for i, n := range t.nodes {
+		if n == node && i > 0 {
+			t.nodes = append([]*url.URL{node}, append(t.nodes[:i:i], t.nodes[i+1:]...)...)
+			break
+		}
+	}
+}
+
+func (t *failoverTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	nodes := append([]*url.URL{}, t.nodes...)
+	t.mu.Unlock()
+
+	if len(nodes) == 0 {
+		resp, err := t.base.RoundTrip(r)
+		if err == nil {
+			t.setServed(r, r.URL)
+		}
+		return resp, err
+	}
+
+	// The body must be replayed for every node so it's buffered up front
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var resp *http.Response
+	var err error
+	for i, node := range nodes {
+		req := r.Clone(r.Context())
+		req.URL.Scheme = node.Scheme
+		req.URL.Host = node.Host
+		req.Host = node.Host
+		if r.Body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err = t.base.RoundTrip(req)
+		last := i == len(nodes)-1
+		if err != nil && connectionError(err) && !last {
+			logging.L().Named("client").Warn("Vault node unreachable, failing over", "address", node.Host,
+				"error", err)
+			continue
+		}
+		if err == nil && resp.StatusCode == http.StatusServiceUnavailable && !last {
+			logging.L().Named("client").Warn("Vault node unavailable, failing over", "address", node.Host,
+				"status", resp.Status)
+			resp.Body.Close()
+			continue
+		}
+
+		if err == nil {
+			t.setServed(r, node)
+		}
+		return resp, err
+	}
+
+	return resp, err
+}
+
+// connectionError returns true if the given error occurred while connecting to a node, meaning the request was never
+// sent and can safely be sent to another node.
+func connectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
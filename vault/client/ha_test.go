@@ -0,0 +1,118 @@
+package client_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newNode returns a test server which responds to health checks with the given status and serves secret/test while
+// unsealed.
+func newNode(sealed bool, standby bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/sys/health":
+			_, _ = fmt.Fprintf(w, `{"initialized": true, "sealed": %t, "standby": %t}`, sealed, standby)
+		case sealed:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors": ["Vault is sealed"]}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": {"node": "ok"}}`))
+		}
+	}))
+}
+
+func newFailoverClient(t *testing.T) *client.VaultClient {
+	t.Helper()
+	vaultClient, err := client.NewClient(&api.Config{Address: "http://127.0.0.1:1", MaxRetries: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vaultClient
+}
+
+func TestVaultClient_SetAddresses(t *testing.T) {
+	down := newNode(false, false)
+	down.Close()
+	sealed := newNode(true, false)
+	defer sealed.Close()
+	standby := newNode(false, true)
+	defer standby.Close()
+	active := newNode(false, false)
+	defer active.Close()
+
+	t.Run("Test that the active node is preferred", func(t *testing.T) {
+		vaultClient := newFailoverClient(t)
+		health, err := vaultClient.SetAddresses([]string{down.URL, sealed.URL, standby.URL, active.URL})
+		assert.Nil(t, err)
+
+		var order []string
+		for _, node := range health {
+			order = append(order, node.Address)
+		}
+		assert.Equal(t, []string{active.URL, standby.URL, sealed.URL, down.URL}, order)
+		assert.True(t, health[0].Active())
+		assert.NotNil(t, health[3].Err)
+		assert.Equal(t, active.URL, vaultClient.Address())
+		assert.Equal(t, health, vaultClient.Nodes())
+
+		_, err = vaultClient.ReadSecret("secret/test")
+		assert.Nil(t, err)
+		assert.Equal(t, active.URL, vaultClient.Node())
+	})
+
+	t.Run("Test with no available nodes", func(t *testing.T) {
+		vaultClient := newFailoverClient(t)
+		_, err := vaultClient.SetAddresses([]string{down.URL, sealed.URL})
+		assert.True(t, errors.Is(err, client.ErrNoAvailableNodes))
+	})
+
+	t.Run("Test with a client created from an API client", func(t *testing.T) {
+		apiClient, err := api.NewClient(&api.Config{Address: active.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.NewClientWithAPI(apiClient).SetAddresses([]string{active.URL})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestVaultClient_Failover(t *testing.T) {
+	first := newNode(false, false)
+	second := newNode(false, true)
+	defer second.Close()
+
+	vaultClient := newFailoverClient(t)
+	if _, err := vaultClient.SetAddresses([]string{first.URL, second.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test with the preferred node", func(t *testing.T) {
+		data, err := vaultClient.ReadSecret("secret/test")
+		assert.Nil(t, err)
+		assert.Equal(t, "ok", data["node"])
+		assert.Equal(t, first.URL, vaultClient.Node())
+	})
+
+	t.Run("Test after the preferred node goes down", func(t *testing.T) {
+		first.Close()
+
+		data, err := vaultClient.ReadSecret("secret/test")
+		assert.Nil(t, err)
+		assert.Equal(t, "ok", data["node"])
+		assert.Equal(t, second.URL, vaultClient.Node())
+	})
+
+	t.Run("Test writes fail over with their body", func(t *testing.T) {
+		_, err := vaultClient.TransitEncrypt("transit", "test", []byte("data"))
+		// The test node doesn't implement transit, but the request must have been served
+		assert.NotNil(t, err)
+		assert.Equal(t, second.URL, vaultClient.Node())
+	})
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+)
+
+// IdentityToken returns an identity token (a JWT) for the entity of the configured token, signed by the identity
+// secrets engine using the given role. The role's key must allow the role's client ID.
+func (c *VaultClient) IdentityToken(ctx context.Context, role string) (string, error) {
+	r := c.api.NewRequest("GET", "/v1/identity/oidc/token/"+role)
+
+	resp, err := c.api.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := api.ParseSecret(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("no identity token was returned from the server")
+	}
+
+	token, ok := secret.Data["token"].(string)
+	if !ok || token == "" {
+		return "", fmt.Errorf("no identity token was returned from the server")
+	}
+
+	return token, nil
+}
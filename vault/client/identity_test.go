@@ -0,0 +1,62 @@
+package client_test
+
+import (
+	"context"
+	"github.com/jmgilman/gcli/internal/mocks"
+	"github.com/jmgilman/gcli/vault/auth"
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func (suite *ClientTestSuite) TestIdentityToken() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+
+	_, err := suite.apiClient.Logical().Write("identity/oidc/key/gcert", map[string]interface{}{
+		"allowed_client_ids": "*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = suite.apiClient.Logical().Write("identity/oidc/role/gcert", map[string]interface{}{"key": "gcert"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = suite.apiClient.Sys().PutPolicy("identity", `path "identity/oidc/token/*" { capabilities = ["read"] }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = suite.apiClient.Logical().Write("auth/userpass/users/identity", map[string]interface{}{
+		"password": "password",
+		"policies": "identity",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiClient, err := suite.apiClient.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultClient := client.NewClientWithAPI(apiClient)
+
+	// Identity tokens can only be issued for tokens with an entity
+	mockAuth := &mocks.AuthMock{
+		GetPathFunc: func(map[string]*auth.Detail) string { return "auth/userpass/login/identity" },
+		GetDataFunc: func(map[string]*auth.Detail) map[string]interface{} { return suite.NewCreds("password") },
+	}
+	if err := vaultClient.Login(mockAuth, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := vaultClient.IdentityToken(context.Background(), "gcert")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+
+	t.Run("Test unknown role", func(t *testing.T) {
+		_, err := vaultClient.IdentityToken(context.Background(), "unknown")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"strings"
+)
+
+// IssueCertificate requests a new certificate and private key from the PKI secrets engine at the given mount point
+// using the given role. The altNames and ttl are optional and are ignored when empty. An empty mount defaults to "pki".
+func (c *VaultClient) IssueCertificate(mount string, role string, commonName string, altNames []string, ttl string) (*cert.Certificate, error) {
+	data := map[string]interface{}{
+		"common_name": commonName,
+	}
+	if len(altNames) > 0 {
+		data["alt_names"] = strings.Join(altNames, ",")
+	}
+	if ttl != "" {
+		data["ttl"] = ttl
+	}
+
+	return c.writePKICertificate(fmt.Sprintf("%s/issue/%s", pkiMount(mount), role), commonName, data)
+}
+
+// SignCertificate sends the given PEM encoded certificate signing request to the PKI secrets engine at the given mount
+// point to be signed using the given role. Only the CSR is sent, so the returned cert.Certificate never contains a
+// private key. The altNames and ttl are optional and are ignored when empty. An empty mount defaults to "pki".
+func (c *VaultClient) SignCertificate(mount string, role string, csr []byte, commonName string, altNames []string, ttl string) (*cert.Certificate, error) {
+	data := map[string]interface{}{
+		"csr":         string(csr),
+		"common_name": commonName,
+	}
+	if len(altNames) > 0 {
+		data["alt_names"] = strings.Join(altNames, ",")
+	}
+	if ttl != "" {
+		data["ttl"] = ttl
+	}
+
+	return c.writePKICertificate(fmt.Sprintf("%s/sign/%s", pkiMount(mount), role), commonName, data)
+}
+
+// RevokeCertificate revokes the certificate with the given serial number using the PKI secrets engine at the given
+// mount point. An empty mount defaults to "pki".
+func (c *VaultClient) RevokeCertificate(mount string, serial string) error {
+	data := map[string]interface{}{
+		"serial_number": serial,
+	}
+
+	_, err := c.api.Logical().Write(pkiMount(mount)+"/revoke", data)
+	return err
+}
+
+// PKICACertificate returns the PEM encoded CA certificate of the PKI secrets engine at the given mount point. An empty
+// mount defaults to "pki".
+func (c *VaultClient) PKICACertificate(mount string) ([]byte, error) {
+	data, err := c.ReadSecret(pkiMount(mount) + "/cert/ca")
+	if err != nil {
+		return []byte{}, err
+	}
+
+	ca, ok := data["certificate"].(string)
+	if !ok || ca == "" {
+		return []byte{}, fmt.Errorf("no CA certificate was returned from the server")
+	}
+
+	return []byte(ca + "\n"), nil
+}
+
+// writePKICertificate writes the given data to the given PKI path and converts the returned certificate into a
+// cert.Certificate.
+func (c *VaultClient) writePKICertificate(path string, commonName string, data map[string]interface{}) (*cert.Certificate, error) {
+	secret, err := c.api.Logical().Write(path, data)
+	if err != nil {
+		return &cert.Certificate{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return &cert.Certificate{}, fmt.Errorf("no certificate was returned from the server")
+	}
+
+	certificate, ok := secret.Data["certificate"].(string)
+	if !ok || certificate == "" {
+		return &cert.Certificate{}, fmt.Errorf("no certificate was returned from the server")
+	}
+
+	result := &cert.Certificate{
+		Domain:      commonName,
+		Certificate: []byte(certificate + "\n"),
+	}
+	result.SerialNumber, _ = secret.Data["serial_number"].(string)
+	if key, ok := secret.Data["private_key"].(string); ok && key != "" {
+		result.PrivateKey = []byte(key + "\n")
+	}
+
+	// Prefer the full CA chain when the engine has one configured, otherwise fall back to the issuing CA
+	if chain, ok := secret.Data["ca_chain"].([]interface{}); ok && len(chain) > 0 {
+		for _, ca := range chain {
+			result.IssuerCertificate = append(result.IssuerCertificate, []byte(fmt.Sprint(ca)+"\n")...)
+		}
+	} else if ca, ok := secret.Data["issuing_ca"].(string); ok {
+		result.IssuerCertificate = []byte(ca + "\n")
+	}
+
+	return result, nil
+}
+
+// pkiMount returns the given mount or the default PKI mount point if it's empty.
+func pkiMount(mount string) string {
+	if mount == "" {
+		return "pki"
+	}
+	return mount
+}
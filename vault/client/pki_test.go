@@ -0,0 +1,91 @@
+package client_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func (suite *ClientTestSuite) TestIssueCertificate() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	t.Run("Test with a valid role", func(t *testing.T) {
+		result, err := vaultClient.IssueCertificate("pki", "test", "host.lab", []string{"alt.lab"}, "30m")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, result.PrivateKey)
+		assert.NotEmpty(t, result.IssuerCertificate)
+		assert.NotEmpty(t, result.SerialNumber)
+
+		block, _ := pem.Decode(result.Certificate)
+		if block == nil {
+			t.Fatal("certificate is not PEM encoded")
+		}
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		assert.Nil(t, err)
+		assert.Equal(t, "host.lab", parsed.Subject.CommonName)
+		assert.Contains(t, parsed.DNSNames, "alt.lab")
+	})
+	t.Run("Test with a disallowed name", func(t *testing.T) {
+		_, err := vaultClient.IssueCertificate("pki", "test", "host.example.com", nil, "")
+		assert.NotNil(t, err)
+	})
+}
+
+func (suite *ClientTestSuite) TestSignCertificate() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	// Every key type offered by pki sign must be accepted by the PKI secrets engine
+	for _, keyType := range cert.KeyTypes {
+		t.Run("Test with key type "+keyType, func(t *testing.T) {
+			key, err := cert.NewKey(keyType, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr, err := cert.NewCSR(key, "signed.lab", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := vaultClient.SignCertificate("pki", "sign", csr, "signed.lab", nil, "")
+			assert.Nil(t, err)
+			assert.Empty(t, result.PrivateKey)
+
+			block, _ := pem.Decode(result.Certificate)
+			if block == nil {
+				t.Fatal("certificate is not PEM encoded")
+			}
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			assert.Nil(t, err)
+			assert.Equal(t, key.Public(), parsed.PublicKey)
+		})
+	}
+}
+
+func (suite *ClientTestSuite) TestRevokeCertificate() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	result, err := vaultClient.IssueCertificate("pki", "test", "revoke.lab", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, vaultClient.RevokeCertificate("pki", result.SerialNumber))
+}
+
+func (suite *ClientTestSuite) TestPKICACertificate() {
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	result, err := vaultClient.PKICACertificate("")
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), string(result), "BEGIN CERTIFICATE")
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/retry"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SetRetryPolicy configures the underlying API client to attempt each request the number of times given by the policy,
+// waiting between attempts with the policy's backoff. Only requests which fail with a transient error are retried, and
+// each retry is logged at the debug level.
+func (c *VaultClient) SetRetryPolicy(p *retry.Policy) {
+	retries := p.MaxAttempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+
+	c.api.SetMaxRetries(retries)
+	c.api.SetBackoff(backoff(p))
+	c.api.SetCheckRetry(checkRetry(p))
+}
+
+// backoff returns a retryablehttp.Backoff which waits according to the given policy, ignoring the wait limits set by
+// the underlying API client.
+func backoff(p *retry.Policy) retryablehttp.Backoff {
+	return func(_, _ time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		// The attempt number starts at zero for the first failed attempt
+		wait := p.Backoff(attemptNum + 1)
+
+		reason := "unable to connect"
+		if resp != nil {
+			reason = resp.Status
+		}
+		logging.L().Named("client").Debug("Vault request failed, retrying", "reason", reason, "wait", wait,
+			"attempt", attemptNum+2, "max_attempts", p.MaxAttempts)
+
+		return wait
+	}
+}
+
+// checkRetry returns a retryablehttp.CheckRetry which retries requests that failed with a transient error according to
+// the retry package. Writes aren't idempotent (i.e. issuing a certificate), so they're only retried when they provably
+// never reached Vault or Vault refused them without acting on them. Untrusted certificates are never retried since
+// retrying won't make them trusted.
+func checkRetry(p *retry.Policy) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		var untrusted *UntrustedCertificateError
+		if errors.As(err, &untrusted) {
+			return false, err
+		}
+
+		// The HTTP client reports the method of failed requests as the Op of a url.Error
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) && !retry.Idempotent(urlErr.Op) {
+				return retry.NotSent(err), nil
+			}
+			return retry.Retryable(err), nil
+		}
+		if resp.Request != nil && !retry.Idempotent(resp.Request.Method) {
+			return retry.UnprocessedStatus(resp.StatusCode), nil
+		}
+		return retry.RetryableStatus(resp.StatusCode), nil
+	}
+}
@@ -0,0 +1,116 @@
+package client_test
+
+import (
+	"bytes"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/retry"
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newFlakyServer returns a test server which responds to the first failures requests with the given status code and
+// to the rest as an unsealed Vault server. The number of requests received is counted in requests.
+func newFlakyServer(failures int32, code int, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(requests, 1) <= failures {
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(`{"errors": ["failed"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"sealed": false, "initialized": true}`))
+	}))
+}
+
+func TestVaultClient_SetRetryPolicy(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, "debug", logging.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logging.SetDefault(logger)
+	defer logging.SetDefault(hclog.NewNullLogger())
+
+	policy := &retry.Policy{MaxAttempts: 3}
+
+	newClient := func(t *testing.T, server *httptest.Server) *client.VaultClient {
+		vaultClient, err := client.NewClient(&api.Config{Address: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vaultClient.SetRetryPolicy(policy)
+		return vaultClient
+	}
+
+	t.Run("Test with a transient error", func(t *testing.T) {
+		var requests int32
+		server := newFlakyServer(2, http.StatusServiceUnavailable, &requests)
+		defer server.Close()
+
+		available, err := newClient(t, server).Available()
+		assert.Nil(t, err)
+		assert.True(t, available)
+		assert.Equal(t, int32(3), requests)
+		assert.Contains(t, logs.String(), `[DEBUG] gcli.client: Vault request failed, retrying: reason="503 Service Unavailable"`)
+	})
+	t.Run("Test with too many transient errors", func(t *testing.T) {
+		var requests int32
+		server := newFlakyServer(3, http.StatusServiceUnavailable, &requests)
+		defer server.Close()
+
+		_, err := newClient(t, server).Available()
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(3), requests)
+	})
+	t.Run("Test with a write which may have been processed", func(t *testing.T) {
+		var requests int32
+		server := newFlakyServer(1, http.StatusBadGateway, &requests)
+		defer server.Close()
+
+		err := newClient(t, server).RevokeCertificate("pki", "01")
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), requests)
+	})
+	t.Run("Test with a write which was refused", func(t *testing.T) {
+		var requests int32
+		server := newFlakyServer(1, http.StatusServiceUnavailable, &requests)
+		defer server.Close()
+
+		err := newClient(t, server).RevokeCertificate("pki", "01")
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), requests)
+	})
+	t.Run("Test with a write whose connection was reset", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				// Reset the connection instead of closing it cleanly
+				_ = conn.(*net.TCPConn).SetLinger(0)
+				conn.Close()
+			}
+		}))
+		defer server.Close()
+
+		err := newClient(t, server).RevokeCertificate("pki", "01")
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), requests)
+	})
+	t.Run("Test with an error that isn't transient", func(t *testing.T) {
+		var requests int32
+		server := newFlakyServer(1, http.StatusInternalServerError, &requests)
+		defer server.Close()
+
+		_, err := newClient(t, server).Available()
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), requests)
+	})
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"net/http"
+)
+
+// UntrustedCertificateError is returned when the Vault server presents a TLS certificate which can't be verified.
+type UntrustedCertificateError struct {
+	Host string
+	Err  error
+}
+
+func (e *UntrustedCertificateError) Error() string {
+	return fmt.Sprintf("the Vault server at %s presented an untrusted TLS certificate (%s); configure the CA which "+
+		"issued it with --vault-ca-cert or --vault-ca-path, or set --vault-tls-server-name if the certificate was "+
+		"issued for a different name", e.Host, e.Err)
+}
+
+func (e *UntrustedCertificateError) Unwrap() error {
+	return e.Err
+}
+
+// NewDefaultClientWithTLS returns a new VaultClient with the underlying API client configured with the Vault default
+// values and the given TLS configuration. Only the values set in the given TLS configuration override the defaults
+// (i.e. VAULT_CACERT).
+func NewDefaultClientWithTLS(t *api.TLSConfig) (*VaultClient, error) {
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return &VaultClient{}, config.Error
+	}
+
+	if err := config.ConfigureTLS(t); err != nil {
+		return &VaultClient{}, err
+	}
+
+	return NewClient(config)
+}
+
+// tlsErrorTransport wraps an http.RoundTripper, converting certificate verification errors into an
+// UntrustedCertificateError.
+type tlsErrorTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tlsErrorTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(r)
+	if err != nil && untrustedCertificate(err) {
+		return resp, &UntrustedCertificateError{Host: r.URL.Host, Err: err}
+	}
+	return resp, err
+}
+
+// untrustedCertificate returns true if the given error was caused by a failure to verify a TLS certificate.
+func untrustedCertificate(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
@@ -0,0 +1,73 @@
+package client_test
+
+import (
+	"encoding/pem"
+	"errors"
+	"github.com/hashicorp/vault/api"
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNewDefaultClientWithTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"sealed": false, "initialized": true}`))
+	}))
+	defer server.Close()
+
+	caFile, err := ioutil.TempFile("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	err = pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile.Close()
+
+	newClient := func(t *testing.T, tlsConfig *api.TLSConfig) *client.VaultClient {
+		vaultClient, err := client.NewDefaultClientWithTLS(tlsConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vaultClient.SetConfigValues(server.URL, ""); err != nil {
+			t.Fatal(err)
+		}
+		return vaultClient
+	}
+
+	t.Run("Test with an untrusted certificate", func(t *testing.T) {
+		_, err := newClient(t, &api.TLSConfig{}).Available()
+
+		var untrusted *client.UntrustedCertificateError
+		assert.True(t, errors.As(err, &untrusted))
+		assert.Contains(t, err.Error(), "--vault-ca-cert")
+	})
+	t.Run("Test with a trusted CA", func(t *testing.T) {
+		available, err := newClient(t, &api.TLSConfig{CACert: caFile.Name()}).Available()
+		assert.Nil(t, err)
+		assert.True(t, available)
+	})
+	t.Run("Test with a mismatched server name", func(t *testing.T) {
+		tlsConfig := &api.TLSConfig{CACert: caFile.Name(), TLSServerName: "vault.lab"}
+		_, err := newClient(t, tlsConfig).Available()
+
+		var untrusted *client.UntrustedCertificateError
+		assert.True(t, errors.As(err, &untrusted))
+	})
+	t.Run("Test with verification disabled", func(t *testing.T) {
+		available, err := newClient(t, &api.TLSConfig{Insecure: true}).Available()
+		assert.Nil(t, err)
+		assert.True(t, available)
+	})
+	t.Run("Test with a client certificate missing its key", func(t *testing.T) {
+		_, err := client.NewDefaultClientWithTLS(&api.TLSConfig{ClientCert: caFile.Name()})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// TransitEncrypt encrypts the given plaintext with the given key using the transit secrets engine at the given mount
+// point and returns the resulting ciphertext (i.e. vault:v1:...). An empty mount defaults to "transit".
+func (c *VaultClient) TransitEncrypt(mount string, key string, plaintext []byte) (string, error) {
+	data := map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	}
+
+	return c.writeTransit(transitMount(mount)+"/encrypt/"+key, data, "ciphertext")
+}
+
+// TransitDecrypt decrypts the given ciphertext with the given key using the transit secrets engine at the given mount
+// point and returns the resulting plaintext. An empty mount defaults to "transit".
+func (c *VaultClient) TransitDecrypt(mount string, key string, ciphertext string) ([]byte, error) {
+	data := map[string]interface{}{
+		"ciphertext": ciphertext,
+	}
+
+	plaintext, err := c.writeTransit(transitMount(mount)+"/decrypt/"+key, data, "plaintext")
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+// TransitRewrap re-encrypts the given ciphertext with the latest version of the given key using the transit secrets
+// engine at the given mount point, without exposing the plaintext. An empty mount defaults to "transit".
+func (c *VaultClient) TransitRewrap(mount string, key string, ciphertext string) (string, error) {
+	data := map[string]interface{}{
+		"ciphertext": ciphertext,
+	}
+
+	return c.writeTransit(transitMount(mount)+"/rewrap/"+key, data, "ciphertext")
+}
+
+// TransitSign signs the given input with the given key using the transit secrets engine at the given mount point and
+// returns the resulting signature (i.e. vault:v1:...). An empty mount defaults to "transit".
+func (c *VaultClient) TransitSign(mount string, key string, input []byte) (string, error) {
+	data := map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(input),
+	}
+
+	return c.writeTransit(transitMount(mount)+"/sign/"+key, data, "signature")
+}
+
+// TransitVerify verifies the given signature of the given input with the given key using the transit secrets engine at
+// the given mount point. An empty mount defaults to "transit".
+func (c *VaultClient) TransitVerify(mount string, key string, input []byte, signature string) (bool, error) {
+	data := map[string]interface{}{
+		"input":     base64.StdEncoding.EncodeToString(input),
+		"signature": signature,
+	}
+
+	secret, err := c.api.Logical().Write(transitMount(mount)+"/verify/"+key, data)
+	if err != nil {
+		return false, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return false, fmt.Errorf("no verification result was returned from the server")
+	}
+
+	valid, ok := secret.Data["valid"].(bool)
+	if !ok {
+		return false, fmt.Errorf("no verification result was returned from the server")
+	}
+
+	return valid, nil
+}
+
+// TransitDataKey generates a new 256-bit data key with the given key using the transit secrets engine at the given
+// mount point. It returns both the plaintext data key and the data key encrypted (wrapped) by the transit key. An empty
+// mount defaults to "transit".
+func (c *VaultClient) TransitDataKey(mount string, key string) ([]byte, string, error) {
+	secret, err := c.api.Logical().Write(transitMount(mount)+"/datakey/plaintext/"+key, map[string]interface{}{})
+	if err != nil {
+		return []byte{}, "", err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return []byte{}, "", fmt.Errorf("no data key was returned from the server")
+	}
+
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok || plaintext == "" {
+		return []byte{}, "", fmt.Errorf("no data key was returned from the server")
+	}
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok || ciphertext == "" {
+		return []byte{}, "", fmt.Errorf("no data key was returned from the server")
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		return []byte{}, "", err
+	}
+
+	return dataKey, ciphertext, nil
+}
+
+// writeTransit writes the given data to the given transit path and returns the given string field from the response.
+// The field may be empty (i.e. the plaintext of an empty input), but must be present.
+func (c *VaultClient) writeTransit(path string, data map[string]interface{}, field string) (string, error) {
+	secret, err := c.api.Logical().Write(path, data)
+	if err != nil {
+		return "", err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("no %s was returned from the server", field)
+	}
+
+	value, ok := secret.Data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("no %s was returned from the server", field)
+	}
+
+	return value, nil
+}
+
+// transitMount returns the given mount or the default transit mount point if it's empty.
+func transitMount(mount string) string {
+	if mount == "" {
+		return "transit"
+	}
+	return mount
+}
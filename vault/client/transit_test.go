@@ -0,0 +1,78 @@
+package client_test
+
+import (
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func (suite *ClientTestSuite) TestTransitEncryptDecrypt() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	ciphertext, err := vaultClient.TransitEncrypt("transit", "test", []byte("secret"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "vault:v1:"))
+
+	t.Run("Test decrypting", func(t *testing.T) {
+		plaintext, err := vaultClient.TransitDecrypt("transit", "test", ciphertext)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("secret"), plaintext)
+	})
+	t.Run("Test rewrapping", func(t *testing.T) {
+		rewrapped, err := vaultClient.TransitRewrap("", "test", ciphertext)
+		assert.Nil(t, err)
+
+		plaintext, err := vaultClient.TransitDecrypt("", "test", rewrapped)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("secret"), plaintext)
+	})
+	t.Run("Test empty plaintext", func(t *testing.T) {
+		empty, err := vaultClient.TransitEncrypt("transit", "test", []byte{})
+		assert.Nil(t, err)
+
+		plaintext, err := vaultClient.TransitDecrypt("transit", "test", empty)
+		assert.Nil(t, err)
+		assert.Empty(t, plaintext)
+	})
+	t.Run("Test decrypting invalid ciphertext", func(t *testing.T) {
+		_, err := vaultClient.TransitDecrypt("transit", "test", "vault:v1:invalid")
+		assert.NotNil(t, err)
+	})
+}
+
+func (suite *ClientTestSuite) TestTransitSignVerify() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	signature, err := vaultClient.TransitSign("transit", "signer", []byte("message"))
+	assert.Nil(t, err)
+
+	t.Run("Test with a valid signature", func(t *testing.T) {
+		valid, err := vaultClient.TransitVerify("transit", "signer", []byte("message"), signature)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+	})
+	t.Run("Test with a modified message", func(t *testing.T) {
+		valid, err := vaultClient.TransitVerify("transit", "signer", []byte("modified"), signature)
+		assert.Nil(t, err)
+		assert.False(t, valid)
+	})
+}
+
+func (suite *ClientTestSuite) TestTransitDataKey() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	dataKey, wrapped, err := vaultClient.TransitDataKey("transit", "test")
+	assert.Nil(t, err)
+	assert.Len(t, dataKey, 32)
+
+	unwrapped, err := vaultClient.TransitDecrypt("transit", "test", wrapped)
+	assert.Nil(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+}
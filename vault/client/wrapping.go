@@ -0,0 +1,87 @@
+package client
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"time"
+)
+
+// WrapPath is the creation path of every wrapping token created by Wrap. Receivers should check that a token they were
+// handed has this creation path before unwrapping it, as any other path means the token didn't come from Wrap.
+const WrapPath = "sys/wrapping/wrap"
+
+// Wrap wraps the given data in a single-use response wrapping token which is valid for the given TTL (i.e. 30m). The
+// data can be retrieved exactly once by passing the token to Unwrap.
+func (c *VaultClient) Wrap(data map[string]interface{}, ttl string) (*api.SecretWrapInfo, error) {
+	r := c.api.NewRequest("POST", "/v1/"+WrapPath)
+	r.WrapTTL = ttl
+	if err := r.SetJSONBody(data); err != nil {
+		return &api.SecretWrapInfo{}, err
+	}
+
+	resp, err := c.api.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return &api.SecretWrapInfo{}, err
+	}
+
+	secret, err := api.ParseSecret(resp.Body)
+	if err != nil {
+		return &api.SecretWrapInfo{}, err
+	}
+
+	if secret == nil || secret.WrapInfo == nil || secret.WrapInfo.Token == "" {
+		return &api.SecretWrapInfo{}, fmt.Errorf("no wrapping token was returned from the server")
+	}
+
+	return secret.WrapInfo, nil
+}
+
+// Unwrap returns the data wrapped by the given wrapping token. Since wrapping tokens are single-use, the token is
+// invalid after this call succeeds.
+func (c *VaultClient) Unwrap(token string) (map[string]interface{}, error) {
+	secret, err := c.api.Logical().Unwrap(token)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return map[string]interface{}{}, fmt.Errorf("no data was returned from the server")
+	}
+
+	return secret.Data, nil
+}
+
+// LookupWrappingToken returns the properties of the given wrapping token without unwrapping it. This can be used to
+// validate a token (i.e. its creation path) before unwrapping it.
+func (c *VaultClient) LookupWrappingToken(token string) (*api.SecretWrapInfo, error) {
+	secret, err := c.api.Logical().Write("sys/wrapping/lookup", map[string]interface{}{"token": token})
+	if err != nil {
+		return &api.SecretWrapInfo{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return &api.SecretWrapInfo{}, fmt.Errorf("no wrapping token information was returned from the server")
+	}
+
+	info := &api.SecretWrapInfo{
+		Token: token,
+	}
+	info.CreationPath, _ = secret.Data["creation_path"].(string)
+
+	if ttl, ok := secret.Data["creation_ttl"]; ok {
+		if _, err := fmt.Sscan(fmt.Sprint(ttl), &info.TTL); err != nil {
+			return &api.SecretWrapInfo{}, fmt.Errorf("unable to parse wrapping token TTL: %w", err)
+		}
+	}
+	if created, ok := secret.Data["creation_time"].(string); ok {
+		info.CreationTime, err = time.Parse(time.RFC3339Nano, created)
+		if err != nil {
+			return &api.SecretWrapInfo{}, fmt.Errorf("unable to parse wrapping token creation time: %w", err)
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,39 @@
+package client_test
+
+import (
+	"github.com/jmgilman/gcli/vault/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func (suite *ClientTestSuite) TestWrap() {
+	t := suite.T()
+	suite.apiClient.SetToken(suite.rootToken)
+	vaultClient := client.NewClientWithAPI(suite.apiClient)
+
+	info, err := vaultClient.Wrap(map[string]interface{}{"secret_id": "test"}, "5m")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, info.Token)
+	assert.Equal(t, 300, info.TTL)
+
+	t.Run("Test looking up the wrapping token", func(t *testing.T) {
+		lookup, err := vaultClient.LookupWrappingToken(info.Token)
+		assert.Nil(t, err)
+		assert.Equal(t, client.WrapPath, lookup.CreationPath)
+		assert.Equal(t, 300, lookup.TTL)
+		assert.False(t, lookup.CreationTime.IsZero())
+	})
+	t.Run("Test unwrapping the wrapping token", func(t *testing.T) {
+		data, err := vaultClient.Unwrap(info.Token)
+		assert.Nil(t, err)
+		assert.Equal(t, "test", data["secret_id"])
+	})
+	t.Run("Test unwrapping the wrapping token twice", func(t *testing.T) {
+		_, err := vaultClient.Unwrap(info.Token)
+		assert.NotNil(t, err)
+	})
+	t.Run("Test looking up an invalid token", func(t *testing.T) {
+		_, err := vaultClient.LookupWrappingToken("invalid")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,100 @@
+package watch
+
+import (
+	"encoding/json"
+	"github.com/jmgilman/gcli/files"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is the state of every watched certificate, persisted between runs so failed renewals keep backing off and the
+// last known expiry of each certificate survives a restart. It's safe for concurrent use.
+type State struct {
+	mu           sync.Mutex
+	path         string
+	Certificates map[string]*CertificateState `json:"certificates"`
+}
+
+// CertificateState is the state of a single watched certificate group.
+type CertificateState struct {
+	Domains      []string  `json:"domains"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	NotAfter     time.Time `json:"not_after,omitempty"`
+	LastChecked  time.Time `json:"last_checked,omitempty"`
+	LastRenewed  time.Time `json:"last_renewed,omitempty"`
+	LastAttempt  time.Time `json:"last_attempt,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	Failures     int       `json:"failures"`
+}
+
+// LoadState reads the state persisted at the given path. An empty State is returned if the file doesn't exist. An
+// empty path returns a State which is never persisted.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Certificates: map[string]*CertificateState{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return &State{}, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return &State{}, err
+	}
+	if s.Certificates == nil {
+		s.Certificates = map[string]*CertificateState{}
+	}
+	return s, nil
+}
+
+// Save atomically writes the state to the path it was loaded from.
+func (s *State) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	_, err = files.WriteAtomic(s.path, append(data, '\n'), 0600)
+	return err
+}
+
+// Get returns a copy of the state of the certificate group with the given name.
+func (s *State) Get(name string) CertificateState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.Certificates[name]; ok {
+		return *c
+	}
+	return CertificateState{}
+}
+
+// Update calls the given function with the state of the certificate group with the given name so it can be modified.
+func (s *State) Update(name string, f func(c *CertificateState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Certificates[name]
+	if !ok {
+		c = &CertificateState{}
+		s.Certificates[name] = c
+	}
+	f(c)
+}
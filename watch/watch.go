@@ -0,0 +1,357 @@
+// The watch package contains the certificate renewal daemon run by gcli cert watch. The certificates listed in a
+// manifest are checked periodically and renewed through the gcert service once they're within the renewal window, after
+// which they're written to their output directories and their deploy hooks are run.
+package watch
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/logging"
+	"github.com/jmgilman/gcli/manifest"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The actions taken for a certificate during a check.
+const (
+	// None means the certificate is outside the renewal window.
+	None = "none"
+
+	// Renewed means the certificate was renewed through the gcert service.
+	Renewed = "renewed"
+
+	// Synced means the certificate on disk was replaced by a newer certificate already in Vault.
+	Synced = "synced"
+
+	// BackingOff means the certificate needs renewing but a recent renewal failed, so it wasn't attempted again yet.
+	BackingOff = "backing off"
+
+	// Failed means checking or renewing the certificate failed.
+	Failed = "failed"
+
+	// HookFailed means the certificate was renewed or synced and written, but one of its deploy hooks failed.
+	HookFailed = "hook failed"
+)
+
+const (
+	// FailureBackoff is how long renewal waits after the first failed renewal of a certificate. It doubles for every
+	// failure after it up to MaxFailureBackoff, so a broken certificate doesn't exhaust the Let's Encrypt rate limits.
+	FailureBackoff = time.Hour
+
+	// MaxFailureBackoff is the longest renewal waits after a failed renewal.
+	MaxFailureBackoff = 24 * time.Hour
+)
+
+// Renewer asks the gcert service to issue the certificate of the given group, returning the domains of the certificates
+// written to Vault.
+type Renewer func(ctx context.Context, g manifest.Group) ([]string, error)
+
+// Watcher checks and renews the certificates of manifest groups.
+type Watcher struct {
+	// Source reads certificates from Vault.
+	Source manifest.Source
+
+	// Renew renews a certificate through the gcert service.
+	Renew Renewer
+
+	// RenewBefore is how long before a certificate expires it's renewed.
+	RenewBefore time.Duration
+
+	// State records the outcome of each check.
+	State *State
+
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Result is the outcome of checking a single certificate group.
+type Result struct {
+	Name         string    `json:"name"`
+	Action       string    `json:"action"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	NotAfter     time.Time `json:"not_after,omitempty"`
+	Files        []string  `json:"files"`
+	Error        string    `json:"error,omitempty"`
+
+	// Err is the error the check failed with, classified by the failure package.
+	Err error `json:"-"`
+}
+
+// Check checks the certificate of the given group. The certificate on disk is checked if the group has an output
+// directory, otherwise the certificate in Vault is. If the certificate on disk is within the renewal window but Vault
+// already holds a newer one (i.e. it was renewed by another host), the newer one is written out. Otherwise the
+// certificate is renewed if it's missing or within the renewal window, unless a recent renewal failed.
+func (w *Watcher) Check(ctx context.Context, g manifest.Group) Result {
+	result, err := w.check(ctx, g)
+	if err != nil {
+		result.Action = Failed
+		if failure.Classify(err).Kind == failure.HookFailed {
+			result.Action = HookFailed
+		}
+		result.Error = err.Error()
+		result.Err = err
+	}
+	if result.Files == nil {
+		result.Files = []string{}
+	}
+
+	// Checks interrupted by shutting down aren't recorded
+	if ctx.Err() == nil {
+		w.State.Update(g.Name, func(s *CertificateState) {
+			s.Domains = g.Domains
+			s.LastChecked = w.now()
+			if result.SerialNumber != "" {
+				s.SerialNumber = result.SerialNumber
+				s.NotAfter = result.NotAfter
+			}
+			if result.Action == Renewed || result.Action == Synced || result.Action == HookFailed {
+				s.LastRenewed = w.now()
+			}
+		})
+	}
+
+	return result
+}
+
+func (w *Watcher) check(ctx context.Context, g manifest.Group) (Result, error) {
+	logger := logging.L().Named("watch").With("name", g.Name)
+	result := Result{Name: g.Name, Action: None}
+	now := w.now()
+
+	// gcert stores certificates under the normalized name (i.e. lowercase and punycode)
+	primary, err := cert.NormalizeDomain(g.Domains[0])
+	if err != nil {
+		return result, fmt.Errorf("invalid domain %s: %w", g.Domains[0], err)
+	}
+
+	current, err := w.vaultCertificate(primary)
+	if err != nil {
+		return result, err
+	}
+
+	if g.Output != "" {
+		local, err := localCertificate(g, primary)
+		if err != nil {
+			return result, err
+		}
+
+		if !w.renewable(local, now) {
+			return describe(result, local), nil
+		}
+
+		// Vault may already hold a renewed certificate, in which case it only needs writing out
+		if !w.renewable(current, now) {
+			logger.Info("syncing newer certificate from Vault", "not_after", current.NotAfter)
+			result.Action = Synced
+			result.Files, err = g.Deploy(ctx, w.Source, []string{primary})
+			return describe(result, current), err
+		}
+	} else if !w.renewable(current, now) {
+		return describe(result, current), nil
+	}
+
+	state := w.State.Get(g.Name)
+	if state.Failures > 0 && now.Before(state.LastAttempt.Add(Backoff(state.Failures))) {
+		logger.Debug("backing off after failed renewal", "failures", state.Failures, "last_attempt",
+			state.LastAttempt)
+		result.Action = BackingOff
+		result.Error = state.LastError
+		return describe(result, current), nil
+	}
+
+	logger.Info("renewing certificate", "domains", g.Domains, "endpoint", g.Endpoint)
+	domains, err := w.Renew(ctx, g)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.recordFailure(g.Name, err)
+		}
+		return result, err
+	}
+	w.State.Update(g.Name, func(s *CertificateState) {
+		s.LastAttempt = now
+		s.LastError = ""
+		s.Failures = 0
+	})
+
+	result.Action = Renewed
+	// The certificate was still written if only a hook failed
+	result.Files, err = g.Deploy(ctx, w.Source, domains)
+	if err != nil && failure.Classify(err).Kind != failure.HookFailed {
+		return result, err
+	}
+
+	renewed, vaultErr := w.vaultCertificate(primary)
+	if vaultErr != nil {
+		return result, vaultErr
+	}
+	return describe(result, renewed), err
+}
+
+// renewable returns true if the given certificate is missing or within the renewal window.
+func (w *Watcher) renewable(c *x509.Certificate, now time.Time) bool {
+	return c == nil || c.NotAfter.Sub(now) <= w.RenewBefore
+}
+
+// vaultCertificate returns the certificate stored in Vault for the given domain, or nil if there isn't one.
+func (w *Watcher) vaultCertificate(domain string) (*x509.Certificate, error) {
+	c, err := w.Source.GetCertificate(domain)
+	if err != nil {
+		var e *failure.Error
+		if errors.As(err, &e) && e.Kind == failure.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read certificate for %s from Vault: %w", domain, err)
+	}
+
+	parsed, err := c.X509()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate for %s from Vault: %w", domain, err)
+	}
+	return parsed, nil
+}
+
+// localCertificate returns the certificate of the given normalized domain written to the output directory of the given
+// group, or nil if it hasn't been written yet.
+func localCertificate(g manifest.Group, domain string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(filepath.Join(g.CertificateDir(domain), cert.CertificateFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := cert.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate for %s on disk: %w", domain, err)
+	}
+	return parsed, nil
+}
+
+func (w *Watcher) recordFailure(name string, err error) {
+	w.State.Update(name, func(s *CertificateState) {
+		s.LastAttempt = w.now()
+		s.LastError = err.Error()
+		s.Failures++
+	})
+}
+
+func (w *Watcher) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+// describe fills in the serial number and expiry of the given certificate.
+func describe(r Result, c *x509.Certificate) Result {
+	if c != nil {
+		r.SerialNumber = cert.FormatSerial(c.SerialNumber)
+		r.NotAfter = c.NotAfter
+	}
+	return r
+}
+
+// Backoff returns how long renewal waits after the given number of consecutive failed renewals.
+func Backoff(failures int) time.Duration {
+	backoff := FailureBackoff
+	for i := 1; i < failures && backoff < MaxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxFailureBackoff {
+		return MaxFailureBackoff
+	}
+	return backoff
+}
+
+// Jitter returns the given interval with a random delay of up to the given jitter added, so many hosts started at the
+// same time don't check their certificates in lockstep.
+func Jitter(interval time.Duration, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// Run checks the certificates of the manifest returned by the given function, then checks them again after every
+// interval (with up to the given jitter added) until the given stop channel is closed. The manifest is loaded again and
+// checked immediately whenever a value is received on the given reload channel; if it fails to load, the previous
+// manifest is kept. The results of every round of checks are passed to the given report function and the state is
+// saved after each round. It only returns early if the manifest can't be loaded initially.
+//
+// Stopping doesn't interrupt the checks in progress, so a renewal isn't abandoned after gcert issued the certificate
+// but before it's written out. They finish and are reported, while the checks which haven't started yet are skipped.
+// Cancelling the given context abandons the checks in progress instead.
+func (w *Watcher) Run(ctx context.Context, load func() (*manifest.Manifest, error), interval time.Duration,
+	jitter time.Duration, reload <-chan struct{}, stop <-chan struct{}, report func([]Result)) error {
+	logger := logging.L().Named("watch")
+
+	m, err := load()
+	if err != nil {
+		return err
+	}
+
+	for {
+		results := make([]Result, len(m.Certificates))
+		checked := make([]bool, len(m.Certificates))
+		manifest.Run(ctx, m.Certificates, m.Workers, func(ctx context.Context, i int, g manifest.Group) error {
+			if stopped(stop) {
+				return nil
+			}
+			results[i] = w.Check(ctx, g)
+			checked[i] = true
+			return nil
+		})
+
+		if err := w.State.Save(); err != nil {
+			logger.Error("unable to save state", "error", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var reported []Result
+		for i, r := range results {
+			if checked[i] {
+				reported = append(reported, r)
+			}
+		}
+		report(reported)
+
+		wait := Jitter(interval, jitter)
+		logger.Debug("waiting for next check", "wait", wait)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-stop:
+			return nil
+		case <-reload:
+			reloaded, err := load()
+			if err != nil {
+				logger.Error("unable to reload manifest, keeping the previous one", "error", err)
+				continue
+			}
+			logger.Info("reloaded manifest", "certificates", len(reloaded.Certificates))
+			m = reloaded
+		case <-time.After(wait):
+		}
+	}
+}
+
+// stopped returns true if the given stop channel is closed.
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,350 @@
+package watch
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/jmgilman/gcli/cert"
+	"github.com/jmgilman/gcli/failure"
+	"github.com/jmgilman/gcli/manifest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// newCertificate returns a certificate for the given domain with the given serial number which expires after the given
+// number of days.
+func newCertificate(t *testing.T, domain string, serial int64, days int) *cert.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    now.Add(-24 * time.Hour),
+		NotAfter:     now.Add(time.Duration(days) * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &cert.Certificate{
+		Domain:      domain,
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKey:  []byte("key"),
+	}
+}
+
+// fakeVault stands in for the certificates stored in Vault.
+type fakeVault struct {
+	mu           sync.Mutex
+	certificates map[string]*cert.Certificate
+}
+
+func (v *fakeVault) GetCertificate(domain string) (*cert.Certificate, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c, ok := v.certificates[domain]
+	if !ok {
+		return &cert.Certificate{}, failure.New(failure.NotFound, errors.New("no secret"), "")
+	}
+	return c, nil
+}
+
+func (v *fakeVault) set(c *cert.Certificate) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.certificates[c.Domain] = c
+}
+
+func newWatcher(t *testing.T) (*Watcher, *fakeVault, *int) {
+	vault := &fakeVault{certificates: map[string]*cert.Certificate{}}
+	renewals := 0
+	w := &Watcher{
+		Source: vault,
+		Renew: func(ctx context.Context, g manifest.Group) ([]string, error) {
+			renewals++
+			vault.set(newCertificate(t, g.Domains[0], 100+int64(renewals), 90))
+			return g.Domains, nil
+		},
+		RenewBefore: 30 * 24 * time.Hour,
+		State:       &State{Certificates: map[string]*CertificateState{}},
+		Now:         func() time.Time { return now },
+	}
+	return w, vault, &renewals
+}
+
+func TestWatcher_Check(t *testing.T) {
+	t.Run("Test certificate outside the renewal window", func(t *testing.T) {
+		w, vault, renewals := newWatcher(t)
+		vault.set(newCertificate(t, "example.com", 1, 60))
+
+		result := w.Check(context.Background(), manifest.Group{Name: "web", Domains: []string{"example.com"}})
+		assert.Equal(t, None, result.Action)
+		assert.Equal(t, "01", result.SerialNumber)
+		assert.Equal(t, 0, *renewals)
+		assert.Equal(t, now, w.State.Get("web").LastChecked)
+	})
+	t.Run("Test certificate within the renewal window", func(t *testing.T) {
+		w, vault, renewals := newWatcher(t)
+		vault.set(newCertificate(t, "example.com", 1, 10))
+
+		result := w.Check(context.Background(), manifest.Group{Name: "web", Domains: []string{"example.com"}})
+		assert.Equal(t, Renewed, result.Action)
+		assert.Equal(t, "65", result.SerialNumber)
+		assert.Equal(t, 1, *renewals)
+		assert.Equal(t, now, w.State.Get("web").LastRenewed)
+	})
+	t.Run("Test missing certificate is written to disk", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "gcli")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		w, _, renewals := newWatcher(t)
+		g := manifest.Group{Name: "web", Domains: []string{"example.com"}, Output: dir}
+
+		result := w.Check(context.Background(), g)
+		assert.Equal(t, Renewed, result.Action)
+		assert.Equal(t, 1, *renewals)
+		assert.Contains(t, result.Files, filepath.Join(dir, "example.com", cert.CertificateFile))
+
+		result = w.Check(context.Background(), g)
+		assert.Equal(t, None, result.Action)
+		assert.Equal(t, 1, *renewals)
+	})
+	t.Run("Test newer certificate in Vault is synced", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "gcli")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		w, vault, renewals := newWatcher(t)
+		g := manifest.Group{Name: "web", Domains: []string{"example.com"}, Output: dir}
+		if _, err := cert.Write(g.CertificateDir("example.com"), newCertificate(t, "example.com", 1, 10)); err != nil {
+			t.Fatal(err)
+		}
+		vault.set(newCertificate(t, "example.com", 2, 80))
+
+		result := w.Check(context.Background(), g)
+		assert.Equal(t, Synced, result.Action)
+		assert.Equal(t, "02", result.SerialNumber)
+		assert.Equal(t, 0, *renewals)
+		assert.NotEmpty(t, result.Files)
+	})
+	t.Run("Test failed hook", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "gcli")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		w, _, renewals := newWatcher(t)
+		g := manifest.Group{Name: "web", Domains: []string{"example.com"}, Output: dir, PostHook: "exit 1"}
+
+		result := w.Check(context.Background(), g)
+		assert.Equal(t, HookFailed, result.Action)
+		assert.Equal(t, failure.HookFailed, failure.Classify(result.Err).Kind)
+		assert.Equal(t, "65", result.SerialNumber)
+		assert.NotEmpty(t, result.Files)
+		assert.Equal(t, 1, *renewals)
+		assert.Equal(t, 0, w.State.Get("web").Failures)
+	})
+	t.Run("Test certificate is looked up by its normalized domain", func(t *testing.T) {
+		w, vault, renewals := newWatcher(t)
+		vault.set(newCertificate(t, "xn--bcher-kva.example", 1, 60))
+
+		result := w.Check(context.Background(), manifest.Group{Name: "web", Domains: []string{"Bücher.Example."}})
+		assert.Equal(t, None, result.Action)
+		assert.Equal(t, "01", result.SerialNumber)
+		assert.Equal(t, 0, *renewals)
+	})
+	t.Run("Test invalid domain", func(t *testing.T) {
+		w, _, renewals := newWatcher(t)
+
+		result := w.Check(context.Background(), manifest.Group{Name: "web", Domains: []string{"*.com"}})
+		assert.Equal(t, Failed, result.Action)
+		assert.Equal(t, 0, *renewals)
+	})
+	t.Run("Test failed renewal backs off", func(t *testing.T) {
+		w, _, _ := newWatcher(t)
+		attempts := 0
+		w.Renew = func(ctx context.Context, g manifest.Group) ([]string, error) {
+			attempts++
+			return []string{}, errors.New("rate limited")
+		}
+		g := manifest.Group{Name: "web", Domains: []string{"example.com"}}
+
+		result := w.Check(context.Background(), g)
+		assert.Equal(t, Failed, result.Action)
+		assert.Equal(t, "rate limited", result.Error)
+		assert.EqualError(t, result.Err, "rate limited")
+		assert.Equal(t, 1, w.State.Get("web").Failures)
+
+		result = w.Check(context.Background(), g)
+		assert.Equal(t, BackingOff, result.Action)
+		assert.Equal(t, 1, attempts)
+
+		w.Now = func() time.Time { return now.Add(FailureBackoff) }
+		result = w.Check(context.Background(), g)
+		assert.Equal(t, Failed, result.Action)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 2, w.State.Get("web").Failures)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Hour, Backoff(1))
+	assert.Equal(t, 2*time.Hour, Backoff(2))
+	assert.Equal(t, 16*time.Hour, Backoff(5))
+	assert.Equal(t, MaxFailureBackoff, Backoff(6))
+	assert.Equal(t, MaxFailureBackoff, Backoff(100))
+}
+
+func TestJitter(t *testing.T) {
+	assert.Equal(t, time.Hour, Jitter(time.Hour, 0))
+	for i := 0; i < 100; i++ {
+		wait := Jitter(time.Hour, time.Minute)
+		assert.True(t, wait >= time.Hour && wait < time.Hour+time.Minute)
+	}
+}
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state", "watch.json")
+
+	s, err := LoadState(path)
+	assert.Nil(t, err)
+	assert.Empty(t, s.Certificates)
+
+	s.Update("web", func(c *CertificateState) {
+		c.Failures = 2
+		c.LastAttempt = now
+	})
+	assert.Nil(t, s.Save())
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadState(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, loaded.Get("web").Failures)
+	assert.True(t, now.Equal(loaded.Get("web").LastAttempt))
+}
+
+func TestWatcher_Run(t *testing.T) {
+	w, vault, _ := newWatcher(t)
+	vault.set(newCertificate(t, "a.example.com", 1, 60))
+	vault.set(newCertificate(t, "b.example.com", 2, 60))
+
+	loads := 0
+	load := func() (*manifest.Manifest, error) {
+		loads++
+		domain := "a.example.com"
+		if loads > 1 {
+			domain = "b.example.com"
+		}
+		return &manifest.Manifest{Certificates: []manifest.Group{{Name: domain, Domains: []string{domain}}}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reload := make(chan struct{})
+	var reports [][]Result
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, load, time.Hour, 0, reload, nil, func(results []Result) {
+			reports = append(reports, results)
+			if len(reports) == 2 {
+				cancel()
+				return
+			}
+			go func() { reload <- struct{}{} }()
+		})
+	}()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher didn't stop")
+	}
+
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "a.example.com", reports[0][0].Name)
+	assert.Equal(t, "b.example.com", reports[1][0].Name)
+}
+
+func TestWatcher_Run_Stop(t *testing.T) {
+	w, _, renewals := newWatcher(t)
+	renew := w.Renew
+	renewing := make(chan struct{})
+	finish := make(chan struct{})
+	w.Renew = func(ctx context.Context, g manifest.Group) ([]string, error) {
+		close(renewing)
+		<-finish
+		if ctx.Err() != nil {
+			return []string{}, ctx.Err()
+		}
+		return renew(ctx, g)
+	}
+
+	// Both certificates are missing, so the first is renewed while the second waits for the single worker
+	load := func() (*manifest.Manifest, error) {
+		return &manifest.Manifest{Workers: 1, Certificates: []manifest.Group{
+			{Name: "a", Domains: []string{"a.example.com"}},
+			{Name: "b", Domains: []string{"b.example.com"}},
+		}}, nil
+	}
+
+	stop := make(chan struct{})
+	var reports [][]Result
+	done := make(chan error)
+	go func() {
+		done <- w.Run(context.Background(), load, time.Hour, 0, nil, stop, func(results []Result) {
+			reports = append(reports, results)
+		})
+	}()
+
+	<-renewing
+	close(stop)
+	close(finish)
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher didn't stop")
+	}
+
+	assert.Equal(t, 1, *renewals)
+	assert.Len(t, reports, 1)
+	assert.Len(t, reports[0], 1)
+	assert.Equal(t, "a", reports[0][0].Name)
+	assert.Equal(t, Renewed, reports[0][0].Action)
+	assert.False(t, w.State.Get("a").LastRenewed.IsZero())
+	assert.True(t, w.State.Get("b").LastChecked.IsZero())
+}